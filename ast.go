@@ -9,9 +9,12 @@ import (
 // AST 抽象语法树
 type AST struct {
 	source    string
+	env       *Environment
 	currTok   *Token
 	currIndex int
 	depth     int
+	legacy    bool
+	end       int // 已读取token的结束位置
 
 	Tokens []*Token
 	Err    error
@@ -21,6 +24,7 @@ func NewAST(toks []*Token, s string) *AST {
 	a := &AST{
 		Tokens: toks,
 		source: s,
+		env:    defaultEnv,
 	}
 	if a.Tokens == nil || len(a.Tokens) == 0 {
 		a.Err = errors.New("empty token")
@@ -36,6 +40,9 @@ func (a *AST) ParseExpression() ExprNode {
 	a.depth++ // called depth
 	lhs := a.parsePrimary()
 	r := a.parseBinOpRHS(0, lhs)
+	if r != nil && a.Err == nil && !a.eof() && a.currTok.Value == "?" {
+		r = a.parseConditional(r)
+	}
 	a.depth--
 	if a.depth == 0 && a.currIndex != len(a.Tokens) && a.Err == nil {
 		a.Err = errors.New(
@@ -46,6 +53,7 @@ func (a *AST) ParseExpression() ExprNode {
 }
 
 func (a *AST) getNextToken() *Token {
+	a.end = a.currTok.Offset + len(a.currTok.Value)
 	a.currIndex++
 	if a.currIndex < len(a.Tokens) {
 		a.currTok = a.Tokens[a.currIndex]
@@ -55,13 +63,26 @@ func (a *AST) getNextToken() *Token {
 }
 
 func (a *AST) getTokPrecedence() int {
-	key := a.currTok.Value[0]
-	if p, ok := Operators[key]; ok {
+	if a.currTok.Type != OperatorType {
+		return -1
+	}
+	if p, ok := a.env.Operator(a.currTok.Value); ok {
 		return p.Precedence()
 	}
 	return -1
 }
 
+// isRightAssoc 当前token是否为右结合操作符
+func (a *AST) isRightAssoc() bool {
+	o, ok := a.env.Operator(a.currTok.Value)
+	return ok && operatorAssoc(o) == RightAssoc
+}
+
+// eof 是否已读取完全部token
+func (a *AST) eof() bool {
+	return a.currIndex >= len(a.Tokens)
+}
+
 // 解析Number值
 func (a *AST) parseNumber() NumberExprNode {
 	f64, err := strconv.ParseFloat(a.currTok.Value, 64)
@@ -74,8 +95,20 @@ func (a *AST) parseNumber() NumberExprNode {
 		return NumberExprNode{}
 	}
 	n := NumberExprNode{
-		Val: f64,
-		Str: a.currTok.Value,
+		Val:  f64,
+		Str:  a.currTok.Value,
+		Span: Span{a.currTok.Offset, a.currTok.Offset + len(a.currTok.Value)},
+	}
+	a.getNextToken()
+	return n
+}
+
+// 解析字符串
+func (a *AST) parseString() ExprNode {
+	s, _ := strconv.Unquote(a.currTok.Value)
+	n := StringExprNode{
+		Val:  s,
+		Span: Span{a.currTok.Offset, a.currTok.Offset + len(a.currTok.Value)},
 	}
 	a.getNextToken()
 	return n
@@ -84,11 +117,13 @@ func (a *AST) parseNumber() NumberExprNode {
 // 解析函数或常量
 func (a *AST) parseFunCallerOrConst() ExprNode {
 	name := a.currTok.Value
+	span := Span{a.currTok.Offset, a.currTok.Offset + len(name)}
 	a.getNextToken()
 	// call func，如果下一个节点为"("表示该节点为函数，否则为常量值
 	if a.currTok.Value == "(" {
 		f := FunCallerExprNode{}
-		if _, ok := defFunc[name]; !ok {
+		def, ok := a.env.Func(name)
+		if !ok {
 			a.Err = errors.New(
 				fmt.Sprintf("function `%s` is undefined\n%s",
 					name,
@@ -109,7 +144,6 @@ func (a *AST) parseFunCallerOrConst() ExprNode {
 				exprs = append(exprs, a.ParseExpression())
 			}
 		}
-		def := defFunc[name]
 		// 校验函数参数
 		if def.Argc() >= 0 && len(exprs) != def.Argc() {
 			a.Err = errors.New(
@@ -122,18 +156,20 @@ func (a *AST) parseFunCallerOrConst() ExprNode {
 		a.getNextToken()
 		f.Name = name
 		f.Arg = exprs
+		f.Span = Span{span.Start, a.end}
 		return f
 	}
 
 	// call const
-	if v, ok := defConst[name]; ok {
+	if v, ok := a.env.Const(name); ok {
 		return ConstExprNode{
 			Name: name,
 			Val:  v,
 			Str:  strconv.FormatFloat(v, 'f', 0, 64),
+			Span: span,
 		}
 	} else {
-		return VariableExprNode{Val: name}
+		return VariableExprNode{Val: name, Span: span}
 		//a.Err = errors.New(
 		//	fmt.Sprintf("const `%s` is undefined\n%s",
 		//		name,
@@ -158,6 +194,7 @@ func (a *AST) parseFunCallerOrConst() ExprNode {
 
 // 解析操作符
 func (a *AST) parseOperator() ExprNode {
+	start := a.currTok.Offset
 	if a.currTok.Value == "(" {
 		t := a.getNextToken()
 		if t == nil {
@@ -178,29 +215,76 @@ func (a *AST) parseOperator() ExprNode {
 			return nil
 		}
 		a.getNextToken()
-		return e
-	} else if a.currTok.Value == "-" {
+		// 括号计入子表达式的位置
+		return withSpan(e, Span{start, a.end})
+	} else if a.legacy && (a.currTok.Value == "-" || a.currTok.Value == "!") {
+		op := a.currTok.Value
 		if a.getNextToken() == nil {
 			a.Err = errors.New(
-				fmt.Sprintf("want '0-9' but get '-'\n%s",
+				fmt.Sprintf("want '0-9' but get '%s'\n%s",
+					op,
 					ErrPos(a.source, a.currTok.Offset)))
 			return nil
 		}
+		rhs := a.parsePrimary()
+		if rhs == nil {
+			return nil
+		}
 		bin := OperatorExprNode{
-			Op:  "-",
-			Lhs: NumberExprNode{},
-			Rhs: a.parsePrimary(),
+			Op:   op,
+			Lhs:  NumberExprNode{},
+			Rhs:  rhs,
+			Span: Span{start, SpanOf(rhs).End},
 		}
 		return bin
+	} else if a.isUnaryOperator() {
+		return a.parseUnary()
 	} else {
 		return a.parseNumber()
 	}
 }
 
+// isUnaryOperator 当前token是否为一元操作符
+func (a *AST) isUnaryOperator() bool {
+	o, ok := a.env.Operator(a.currTok.Value)
+	if !ok {
+		return false
+	}
+	_, ok = o.(UnaryOperatorItem)
+	return ok
+}
+
+// 解析一元操作符，操作数吸收权重高于一元操作符的二元运算，如 -2^2 => -(2^2)
+func (a *AST) parseUnary() ExprNode {
+	op := a.currTok.Value
+	start := a.currTok.Offset
+	if a.getNextToken() == nil {
+		a.Err = errors.New(
+			fmt.Sprintf("want '0-9' but get '%s'\n%s",
+				op,
+				ErrPos(a.source, a.currTok.Offset)))
+		return nil
+	}
+	operand := a.parsePrimary()
+	if operand == nil {
+		return nil
+	}
+	operand = a.parseBinOpRHS(UnaryPrecedence+1, operand)
+	if operand == nil {
+		return nil
+	}
+	return UnaryExprNode{
+		Op:      op,
+		Operand: operand,
+		Span:    Span{start, SpanOf(operand).End},
+	}
+}
+
 // 解析变量
 func (a *AST) parseVariable() ExprNode {
 	n := VariableExprNode{
-		Val: a.currTok.Value,
+		Val:  a.currTok.Value,
+		Span: Span{a.currTok.Offset, a.currTok.Offset + len(a.currTok.Value)},
 	}
 	a.getNextToken()
 	return n
@@ -212,9 +296,11 @@ func (a *AST) parsePrimary() ExprNode {
 		return a.parseFunCallerOrConst()
 	case LiteralType:
 		return a.parseNumber()
+	case StringType:
+		return a.parseString()
 	case OperatorType:
 		return a.parseOperator()
-	case CommaType:
+	case CommaType, ConditionalType:
 		a.Err = errors.New(
 			fmt.Sprintf("want '(' or '0-9' but get %s\n%s",
 				a.currTok.Value,
@@ -225,6 +311,49 @@ func (a *AST) parsePrimary() ExprNode {
 	}
 }
 
+// 解析条件运算 cond ? then : else，右结合
+func (a *AST) parseConditional(cond ExprNode) ExprNode {
+	if a.getNextToken() == nil {
+		a.Err = errors.New(
+			fmt.Sprintf("want '(' or '0-9' but get EOF\n%s",
+				ErrPos(a.source, a.currTok.Offset)))
+		return nil
+	}
+	then := a.ParseExpression()
+	if then == nil || a.Err != nil {
+		return nil
+	}
+	if a.eof() {
+		a.Err = errors.New(
+			fmt.Sprintf("want ':' but get EOF\n%s",
+				ErrPos(a.source, a.currTok.Offset+len(a.currTok.Value))))
+		return nil
+	}
+	if a.currTok.Value != ":" {
+		a.Err = errors.New(
+			fmt.Sprintf("want ':' but get %s\n%s",
+				a.currTok.Value,
+				ErrPos(a.source, a.currTok.Offset)))
+		return nil
+	}
+	if a.getNextToken() == nil {
+		a.Err = errors.New(
+			fmt.Sprintf("want '(' or '0-9' but get EOF\n%s",
+				ErrPos(a.source, a.currTok.Offset)))
+		return nil
+	}
+	els := a.ParseExpression()
+	if els == nil {
+		return nil
+	}
+	return ConditionalExprNode{
+		Cond: cond,
+		Then: then,
+		Else: els,
+		Span: Span{SpanOf(cond).Start, SpanOf(els).End},
+	}
+}
+
 func (a *AST) parseBinOpRHS(execPrec int, lhs ExprNode) ExprNode {
 	for {
 		tokPrec := a.getTokPrecedence()
@@ -248,12 +377,19 @@ func (a *AST) parseBinOpRHS(execPrec int, lhs ExprNode) ExprNode {
 			if rhs == nil {
 				return nil
 			}
+		} else if tokPrec == nextPrec && !a.legacy && a.isRightAssoc() {
+			// 右结合，如 2^3^2 => 2^(3^2)
+			rhs = a.parseBinOpRHS(tokPrec, rhs)
+			if rhs == nil {
+				return nil
+			}
 		}
 		lhs = OperatorExprNode{
 			Op:   binOp,
 			Lhs:  lhs,
 			Rhs:  rhs,
 			Flag: false,
+			Span: Span{SpanOf(lhs).Start, SpanOf(rhs).End},
 		}
 	}
 }
@@ -0,0 +1,707 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// maxExactExp 高精度模式下精确计算的最大整数指数，超出时返回ErrInexact
+const maxExactExp = 1 << 16
+
+// RatFunc 以有理数参数精确计算，PrecisionRat及PrecisionBigFloat模式优先使用
+// 未实现时PrecisionRat模式返回ErrInexact，PrecisionBigFloat模式使用BigFloatFunc，均未实现时返回ErrInexact
+type RatFunc interface {
+	CallRat(args []*big.Rat) (*big.Rat, error)
+}
+
+// BigFloatFunc 以big.Float参数计算，参数精度即计算精度，PrecisionBigFloat模式优先使用
+type BigFloatFunc interface {
+	CallBigFloat(args []*big.Float) (*big.Float, error)
+}
+
+// EvaluateRat 以big.Rat精确计算，字面量按源码文本解析
+// 值为整数的常量精确参与计算，pi、e等其他常量、未实现RatFunc的函数、自定义操作符及结果为无理数的幂运算返回ErrInexact
+func EvaluateRat(ctx context.Context, expr ExprNode) (*big.Rat, error) {
+	return newNumEvaluator[*big.Rat](newEvaluator(ctx), ratArith{}).eval(expr)
+}
+
+// EvaluateBigFloat 以big.Float计算，精度及舍入方式由Options.Prec、Options.Rounding指定
+// pi、e按计算精度计算，值为整数的常量精确参与计算，其他常量返回ErrInexact；未实现BigFloatFunc及RatFunc的函数、自定义操作符及非整数指数（0.5除外）的幂运算返回ErrInexact
+func EvaluateBigFloat(ctx context.Context, expr ExprNode) (*big.Float, error) {
+	ev := newEvaluator(ctx)
+	return newNumEvaluator[*big.Float](ev, newBigFloatArith(ev.st.opts)).eval(expr)
+}
+
+// EvaluateString 按Options.Precision计算，结果保留digits位小数
+// digits为负时输出精确值：float64及big.Float输出可还原的最短小数，big.Rat为无限小数时输出分数，定点小数保留全部小数位数
+// 定点小数按计算规则的舍入方式保留digits位小数
+func EvaluateString(ctx context.Context, expr ExprNode, digits int) (string, error) {
+	switch OptionsFrom(ctx).Precision {
+	case PrecisionDecimal:
+		d, err := EvaluateDecimal(ctx, expr)
+		if err != nil {
+			return "", err
+		}
+		if digits >= 0 {
+			d = d.Round(digits, decimalPolicy(ctx, OptionsFrom(ctx)).Rounding)
+		}
+		return d.String(), nil
+	case PrecisionRat:
+		r, err := EvaluateRat(ctx, expr)
+		if err != nil {
+			return "", err
+		}
+		return formatRat(r, digits), nil
+	case PrecisionBigFloat:
+		f, err := EvaluateBigFloat(ctx, expr)
+		if err != nil {
+			return "", err
+		}
+		return f.Text('f', digits), nil
+	}
+	v, err := Evaluate(ctx, expr)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(v, 'f', digits, 64), nil
+}
+
+// formatRat 输出小数，digits为负且为无限小数时输出分数
+func formatRat(r *big.Rat, digits int) string {
+	if digits >= 0 {
+		return r.FloatString(digits)
+	}
+	if n, ok := decimalPlaces(r); ok {
+		return r.FloatString(n)
+	}
+	return r.RatString()
+}
+
+func ratBool(b bool) *big.Rat {
+	if b {
+		return big.NewRat(1, 1)
+	}
+	return new(big.Rat)
+}
+
+// ratArith big.Rat精确计算
+type ratArith struct{}
+
+func (ratArith) literal(n NumberExprNode) (*big.Rat, error) {
+	if n.Str != "" {
+		if r, ok := new(big.Rat).SetString(n.Str); ok {
+			return r, nil
+		}
+	}
+	return ratArith{}.fromFloat(n.Val)
+}
+
+func (ratArith) convert(value any) (*big.Rat, bool) {
+	switch t := value.(type) {
+	case *big.Rat:
+		return new(big.Rat).Set(t), true
+	case *big.Int:
+		return new(big.Rat).SetInt(t), true
+	case *big.Float:
+		if !t.IsInf() {
+			r, _ := t.Rat(nil)
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (ratArith) fromFloat(f float64) (*big.Rat, error) {
+	if !isFinite(f) {
+		return nil, ErrNotFinite
+	}
+	return new(big.Rat).SetFloat64(f), nil
+}
+
+// constant 常量仅在值为整数时可精确表示
+func (ratArith) constant(val float64) (*big.Rat, error) {
+	if val != math.Trunc(val) || !isFinite(val) {
+		return nil, ErrInexact
+	}
+	return new(big.Rat).SetFloat64(val), nil
+}
+
+func (ratArith) toFloat(v *big.Rat) float64 {
+	f, _ := v.Float64()
+	return f
+}
+
+func (ratArith) truth(v *big.Rat) (bool, error) {
+	return v.Sign() != 0, nil
+}
+
+func (ar ratArith) operator(operator OperatorItem, op string, a *big.Rat, b *big.Rat) (*big.Rat, bool, error) {
+	switch operator.(type) {
+	case *Plus:
+		return new(big.Rat).Add(a, b), true, nil
+	case *Minus:
+		return new(big.Rat).Sub(a, b), true, nil
+	case *Mul:
+		return new(big.Rat).Mul(a, b), true, nil
+	case *Div:
+		if b.Sign() == 0 {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: 0, Err: ErrDivisionByZero}
+		}
+		return new(big.Rat).Quo(a, b), true, nil
+	case *Mod:
+		if b.Sign() == 0 {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: 0, Err: ErrDivisionByZero}
+		}
+		return ratMod(a, b), true, nil
+	case *Pow:
+		r, err := ratPowFunc([]*big.Rat{a, b})
+		if err != nil {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: ar.toFloat(b), Err: err}
+		}
+		return r, true, nil
+	}
+	if v, ok := compareResult(operator, a.Cmp(b), a.Sign() != 0, b.Sign() != 0); ok {
+		return ratBool(v), true, nil
+	}
+	return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: ar.toFloat(b), Err: ErrInexact}
+}
+
+func (ar ratArith) unary(operator OperatorItem, op string, a *big.Rat) (*big.Rat, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		return new(big.Rat).Neg(a), true, nil
+	case *Plus:
+		return a, true, nil
+	case *Not:
+		return ratBool(a.Sign() == 0), true, nil
+	}
+	return nil, true, &OperatorError{Op: op, Rhs: ar.toFloat(a), Err: ErrInexact}
+}
+
+func (ratArith) call(def DefFunc, name string, args []*big.Rat) (*big.Rat, bool, error) {
+	if fn := ratFunc(def); fn != nil {
+		v, err := fn(args)
+		return v, true, err
+	}
+	return nil, true, ErrInexact
+}
+
+// compareResult 比较及逻辑运算，cmp为两个操作数的比较结果
+func compareResult(operator OperatorItem, cmp int, a bool, b bool) (bool, bool) {
+	switch operator.(type) {
+	case *Less:
+		return cmp < 0, true
+	case *LessEqual:
+		return cmp <= 0, true
+	case *Greater:
+		return cmp > 0, true
+	case *GreaterEqual:
+		return cmp >= 0, true
+	case *Equal:
+		return cmp == 0, true
+	case *NotEqual:
+		return cmp != 0, true
+	case *And:
+		return a && b, true
+	case *Or:
+		return a || b, true
+	case *Not:
+		return !b, true
+	}
+	return false, false
+}
+
+// ratMod 截断取余 a - b*trunc(a/b)，结果符号与被除数一致，整数时与float64计算一致
+func ratMod(a *big.Rat, b *big.Rat) *big.Rat {
+	q := new(big.Rat).Quo(a, b)
+	t := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+	return new(big.Rat).Sub(a, t.Mul(t, b))
+}
+
+// ratPow 整数指数的幂运算，非整数指数及超出maxExactExp的指数返回false，0的负数次幂返回nil
+func ratPow(a *big.Rat, b *big.Rat) (*big.Rat, bool) {
+	if !b.IsInt() || !b.Num().IsInt64() {
+		return nil, false
+	}
+	n := b.Num().Int64()
+	if n > maxExactExp || n < -maxExactExp {
+		return nil, false
+	}
+	if n < 0 {
+		if a.Sign() == 0 {
+			return nil, true
+		}
+		a, n = new(big.Rat).Inv(a), -n
+	}
+	e := big.NewInt(n)
+	num := new(big.Int).Exp(a.Num(), e, nil)
+	denom := new(big.Int).Exp(a.Denom(), e, nil)
+	return new(big.Rat).SetFrac(num, denom), true
+}
+
+// ratTrunc 向零取整
+func ratTrunc(a *big.Rat) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Quo(a.Num(), a.Denom()))
+}
+
+// ratFloor 向下取整
+func ratFloor(a *big.Rat) *big.Rat {
+	t := ratTrunc(a)
+	if a.Sign() < 0 && t.Cmp(a) != 0 {
+		t.Sub(t, big.NewRat(1, 1))
+	}
+	return t
+}
+
+// ratRound 四舍五入，.5远离零
+func ratRound(a *big.Rat) *big.Rat {
+	half := big.NewRat(1, 2)
+	if a.Sign() < 0 {
+		return ratTrunc(new(big.Rat).Sub(a, half))
+	}
+	return ratTrunc(new(big.Rat).Add(a, half))
+}
+
+// ratFunc 获取函数的有理数实现
+func ratFunc(def DefFunc) func(args []*big.Rat) (*big.Rat, error) {
+	switch t := def.(type) {
+	case RatFunc:
+		return t.CallRat
+	case *stdFunc:
+		return t.rat
+	case *stdDiffFunc:
+		return t.rat
+	}
+	return nil
+}
+
+// bigFloatFunc 获取函数的big.Float实现
+func bigFloatFunc(def DefFunc) func(args []*big.Float) (*big.Float, error) {
+	switch t := def.(type) {
+	case BigFloatFunc:
+		return t.CallBigFloat
+	case *stdFunc:
+		return t.bigFloat
+	case *stdDiffFunc:
+		return t.bigFloat
+	}
+	return nil
+}
+
+// bigFloatArith big.Float计算，每一步运算按指定精度及舍入方式舍入
+type bigFloatArith struct {
+	prec uint
+	mode big.RoundingMode
+}
+
+func newBigFloatArith(opts Options) bigFloatArith {
+	prec := opts.Prec
+	if prec == 0 {
+		prec = DefaultPrec
+	}
+	return bigFloatArith{prec: prec, mode: opts.Rounding}
+}
+
+func (ar bigFloatArith) new() *big.Float {
+	return new(big.Float).SetPrec(ar.prec).SetMode(ar.mode)
+}
+
+func (ar bigFloatArith) literal(n NumberExprNode) (*big.Float, error) {
+	if n.Str != "" {
+		if f, ok := ar.new().SetString(n.Str); ok {
+			return f, nil
+		}
+	}
+	return ar.fromFloat(n.Val)
+}
+
+func (ar bigFloatArith) convert(value any) (*big.Float, bool) {
+	switch t := value.(type) {
+	case *big.Float:
+		if !t.IsInf() {
+			return ar.new().Set(t), true
+		}
+	case *big.Rat:
+		return ar.new().SetRat(t), true
+	case *big.Int:
+		return ar.new().SetInt(t), true
+	}
+	return nil, false
+}
+
+func (ar bigFloatArith) fromFloat(f float64) (*big.Float, error) {
+	if !isFinite(f) {
+		return nil, ErrNotFinite
+	}
+	return ar.new().SetFloat64(f), nil
+}
+
+// constant pi、e按计算精度计算，其他常量仅在值为整数时可精确表示
+func (ar bigFloatArith) constant(val float64) (*big.Float, error) {
+	switch {
+	case val == math.Pi:
+		return ar.new().Set(bigPi(ar.prec)), nil
+	case val == math.E:
+		return ar.new().Set(bigE(ar.prec)), nil
+	case val == math.Trunc(val) && isFinite(val):
+		return ar.new().SetFloat64(val), nil
+	}
+	return nil, ErrInexact
+}
+
+// bigPi 按Machin公式 pi = 16*atan(1/5) - 4*atan(1/239) 计算，中间结果多保留64位精度
+func bigPi(prec uint) *big.Float {
+	p := prec + 64
+	a := new(big.Float).SetPrec(p).Mul(big.NewFloat(16), bigAtanInv(5, p))
+	b := new(big.Float).SetPrec(p).Mul(big.NewFloat(4), bigAtanInv(239, p))
+	return a.Sub(a, b)
+}
+
+// bigAtanInv atan(1/n) = 1/n - 1/(3n^3) + 1/(5n^5) - ...
+func bigAtanInv(n int64, prec uint) *big.Float {
+	n2 := new(big.Float).SetPrec(prec).SetInt64(n * n)
+	x := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), new(big.Float).SetInt64(n))
+	r := new(big.Float).SetPrec(prec).Set(x)
+	t := new(big.Float).SetPrec(prec)
+	limit := -int(prec) - 2
+	for k := int64(3); ; k += 2 {
+		x.Quo(x, n2)
+		if x.Sign() == 0 || x.MantExp(nil) < limit {
+			return r
+		}
+		t.Quo(x, new(big.Float).SetInt64(k))
+		if k%4 == 3 {
+			r.Sub(r, t)
+		} else {
+			r.Add(r, t)
+		}
+	}
+}
+
+// bigE 按级数 e = 1 + 1/1! + 1/2! + ... 计算，中间结果多保留64位精度
+func bigE(prec uint) *big.Float {
+	p := prec + 64
+	r := new(big.Float).SetPrec(p).SetInt64(1)
+	t := new(big.Float).SetPrec(p).SetInt64(1)
+	limit := -int(p) - 2
+	for k := int64(1); ; k++ {
+		t.Quo(t, new(big.Float).SetInt64(k))
+		if t.MantExp(nil) < limit {
+			return r
+		}
+		r.Add(r, t)
+	}
+}
+
+func (ar bigFloatArith) toFloat(v *big.Float) float64 {
+	f, _ := v.Float64()
+	return f
+}
+
+func (ar bigFloatArith) truth(v *big.Float) (bool, error) {
+	return v.Sign() != 0, nil
+}
+
+func (ar bigFloatArith) operator(operator OperatorItem, op string, a *big.Float, b *big.Float) (*big.Float, bool, error) {
+	var z *big.Float
+	switch operator.(type) {
+	case *Plus:
+		z = ar.new().Add(a, b)
+	case *Minus:
+		z = ar.new().Sub(a, b)
+	case *Mul:
+		z = ar.new().Mul(a, b)
+	case *Div:
+		if b.Sign() == 0 {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: 0, Err: ErrDivisionByZero}
+		}
+		z = ar.new().Quo(a, b)
+	case *Mod:
+		if b.Sign() == 0 {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: 0, Err: ErrDivisionByZero}
+		}
+		t, _ := ar.new().Quo(a, b).Int(nil)
+		q := ar.new().SetInt(t)
+		z = ar.new().Sub(a, q.Mul(q, b))
+	case *Pow:
+		var err error
+		if z, err = ar.powFloat(a, b); err != nil {
+			return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: ar.toFloat(b), Err: err}
+		}
+	default:
+		if v, ok := compareResult(operator, a.Cmp(b), a.Sign() != 0, b.Sign() != 0); ok {
+			return ar.new().SetFloat64(boolResult(v)), true, nil
+		}
+		return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: ar.toFloat(b), Err: ErrInexact}
+	}
+	if z.IsInf() {
+		return nil, true, &OperatorError{Op: op, Lhs: ar.toFloat(a), Rhs: ar.toFloat(b), Err: ErrNotFinite}
+	}
+	return z, true, nil
+}
+
+// pow 整数次幂，二进制快速幂
+func (ar bigFloatArith) pow(a *big.Float, n int64) *big.Float {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	// 中间结果使用更高的精度以减少舍入误差
+	x := new(big.Float).SetPrec(ar.prec + 64).Set(a)
+	r := new(big.Float).SetPrec(ar.prec + 64).SetInt64(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r.Mul(r, x)
+		}
+		x.Mul(x, x)
+	}
+	if neg {
+		r.Quo(new(big.Float).SetInt64(1), r)
+	}
+	return ar.new().Set(r)
+}
+
+// powFloat 整数指数以快速幂计算，指数为0.5时开平方，其他指数返回ErrInexact
+func (ar bigFloatArith) powFloat(a *big.Float, b *big.Float) (*big.Float, error) {
+	if n, ok := intExponent(b); ok {
+		if n < 0 && a.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return ar.pow(a, n), nil
+	}
+	if b.Cmp(big.NewFloat(0.5)) == 0 && a.Sign() >= 0 {
+		return ar.new().Sqrt(a), nil
+	}
+	return nil, ErrInexact
+}
+
+// intExponent 获取整数指数，超出maxExactExp时返回false
+func intExponent(b *big.Float) (int64, bool) {
+	if !b.IsInt() {
+		return 0, false
+	}
+	n, acc := b.Int64()
+	if acc != big.Exact || n > maxExactExp || n < -maxExactExp {
+		return 0, false
+	}
+	return n, true
+}
+
+func (ar bigFloatArith) unary(operator OperatorItem, op string, a *big.Float) (*big.Float, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		return ar.new().Neg(a), true, nil
+	case *Plus:
+		return a, true, nil
+	case *Not:
+		return ar.new().SetFloat64(boolResult(a.Sign() == 0)), true, nil
+	}
+	return nil, true, &OperatorError{Op: op, Rhs: ar.toFloat(a), Err: ErrInexact}
+}
+
+func (ar bigFloatArith) call(def DefFunc, name string, args []*big.Float) (*big.Float, bool, error) {
+	if fn := bigFloatFunc(def); fn != nil {
+		v, err := fn(args)
+		if err != nil {
+			return nil, true, err
+		}
+		return ar.new().Set(v), true, nil
+	}
+	// big.Float可精确转换为big.Rat
+	if fn := ratFunc(def); fn != nil {
+		rats := make([]*big.Rat, len(args))
+		for i, a := range args {
+			rats[i], _ = a.Rat(nil)
+		}
+		v, err := fn(rats)
+		if err != nil {
+			return nil, true, err
+		}
+		return ar.new().SetRat(v), true, nil
+	}
+	return nil, true, ErrInexact
+}
+
+// 标准库函数的高精度实现
+
+func ratAbs(args []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Abs(args[0]), nil
+}
+
+func ratSign(args []*big.Rat) (*big.Rat, error) {
+	return big.NewRat(int64(args[0].Sign()), 1), nil
+}
+
+func ratFloorFunc(args []*big.Rat) (*big.Rat, error) {
+	return ratFloor(args[0]), nil
+}
+
+func ratCeil(args []*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Neg(ratFloor(new(big.Rat).Neg(args[0]))), nil
+}
+
+func ratTruncFunc(args []*big.Rat) (*big.Rat, error) {
+	return ratTrunc(args[0]), nil
+}
+
+func ratRoundFunc(args []*big.Rat) (*big.Rat, error) {
+	if len(args) == 1 {
+		return ratRound(args[0]), nil
+	}
+	d := ratTrunc(args[1])
+	if !d.Num().IsInt64() || d.Num().Int64() > maxExactExp || d.Num().Int64() < -maxExactExp {
+		return nil, errors.New(fmt.Sprintf("digits %s out of range", d.RatString()))
+	}
+	p, _ := ratPow(big.NewRat(10, 1), d)
+	r := ratRound(new(big.Rat).Mul(args[0], p))
+	return r.Quo(r, p), nil
+}
+
+func ratClamp(args []*big.Rat) (*big.Rat, error) {
+	if args[1].Cmp(args[2]) > 0 {
+		return nil, errors.New(fmt.Sprintf("lower bound %s is greater than upper bound %s",
+			args[1].RatString(), args[2].RatString()))
+	}
+	switch {
+	case args[0].Cmp(args[1]) < 0:
+		return args[1], nil
+	case args[0].Cmp(args[2]) > 0:
+		return args[2], nil
+	}
+	return args[0], nil
+}
+
+func ratMin(args []*big.Rat) (*big.Rat, error) {
+	r := args[0]
+	for _, v := range args[1:] {
+		if v.Cmp(r) < 0 {
+			r = v
+		}
+	}
+	return r, nil
+}
+
+func ratMax(args []*big.Rat) (*big.Rat, error) {
+	r := args[0]
+	for _, v := range args[1:] {
+		if v.Cmp(r) > 0 {
+			r = v
+		}
+	}
+	return r, nil
+}
+
+func ratSum(args []*big.Rat) (*big.Rat, error) {
+	r := new(big.Rat)
+	for _, v := range args {
+		r.Add(r, v)
+	}
+	return r, nil
+}
+
+func ratAvg(args []*big.Rat) (*big.Rat, error) {
+	r, _ := ratSum(args)
+	return r.Quo(r, big.NewRat(int64(len(args)), 1)), nil
+}
+
+// ratPowFunc 整数指数精确计算，指数为1/2时仅支持完全平方数，其他非整数指数返回ErrInexact
+func ratPowFunc(args []*big.Rat) (*big.Rat, error) {
+	if args[1].Cmp(big.NewRat(1, 2)) == 0 {
+		return ratSqrt(args)
+	}
+	r, ok := ratPow(args[0], args[1])
+	if !ok {
+		return nil, ErrInexact
+	}
+	if r == nil {
+		return nil, ErrDivisionByZero
+	}
+	return r, nil
+}
+
+// ratSqrt 分子分母均为完全平方数时精确计算，否则结果为无理数，返回ErrInexact
+func ratSqrt(args []*big.Rat) (*big.Rat, error) {
+	a := args[0]
+	if a.Sign() < 0 {
+		return nil, ErrNotFinite
+	}
+	num := new(big.Int).Sqrt(a.Num())
+	denom := new(big.Int).Sqrt(a.Denom())
+	r := new(big.Rat).SetFrac(num, denom)
+	if new(big.Rat).Mul(r, r).Cmp(a) != 0 {
+		return nil, ErrInexact
+	}
+	return r, nil
+}
+
+// ratGcd 整数参数的最大公约数，结果非负
+func ratGcd(args []*big.Rat) (*big.Rat, error) {
+	return ratFoldInt(args, func(a, b *big.Int) *big.Int {
+		return new(big.Int).GCD(nil, nil, a, b)
+	})
+}
+
+// ratLcm 整数参数的最小公倍数，结果非负
+func ratLcm(args []*big.Rat) (*big.Rat, error) {
+	return ratFoldInt(args, func(a, b *big.Int) *big.Int {
+		if a.Sign() == 0 || b.Sign() == 0 {
+			return new(big.Int)
+		}
+		g := new(big.Int).GCD(nil, nil, a, b)
+		return g.Mul(new(big.Int).Quo(a, g), b)
+	})
+}
+
+// ratFoldInt 对整数参数的绝对值依次执行fn
+func ratFoldInt(args []*big.Rat, fn func(a, b *big.Int) *big.Int) (*big.Rat, error) {
+	ints := make([]*big.Int, len(args))
+	for i, v := range args {
+		if !v.IsInt() {
+			return nil, errors.New(fmt.Sprintf("integer parameter required but get %s", v.RatString()))
+		}
+		ints[i] = new(big.Int).Abs(v.Num())
+	}
+	r := ints[0]
+	for _, v := range ints[1:] {
+		r = fn(r, v)
+	}
+	return new(big.Rat).SetInt(r), nil
+}
+
+func bigFloatPowFunc(args []*big.Float) (*big.Float, error) {
+	ar := bigFloatArith{prec: args[0].Prec(), mode: args[0].Mode()}
+	return ar.powFloat(args[0], args[1])
+}
+
+func bigFloatSqrt(args []*big.Float) (*big.Float, error) {
+	if args[0].Sign() < 0 {
+		return nil, ErrNotFinite
+	}
+	return new(big.Float).SetPrec(args[0].Prec()).Sqrt(args[0]), nil
+}
+
+// withRat 设置标准库函数的有理数实现
+func withRat(fn func(args []*big.Rat) (*big.Rat, error), f DefFunc) DefFunc {
+	switch t := f.(type) {
+	case *stdFunc:
+		t.rat = fn
+	case *stdDiffFunc:
+		t.rat = fn
+	}
+	return f
+}
+
+// withBigFloat 设置标准库函数的big.Float实现
+func withBigFloat(fn func(args []*big.Float) (*big.Float, error), f DefFunc) DefFunc {
+	switch t := f.(type) {
+	case *stdFunc:
+		t.bigFloat = fn
+	case *stdDiffFunc:
+		t.bigFloat = fn
+	}
+	return f
+}
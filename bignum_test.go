@@ -0,0 +1,145 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEvaluateRat(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"0.1 + 0.2", "3/10"},
+		{"1/3 + 1/6", "1/2"},
+		{"2^-3", "1/8"},
+		{"7 % 3", "1"},
+		{"-7 % 3", "-1"},
+		{"abs(-1/3)", "1/3"},
+		{"floor(-2.5)", "-3"},
+		{"round(2.5)", "3"},
+		{"round(1.25, 1)", "13/10"},
+		{"1/3 > 0.333", "1"},
+		{"sqrt(9/4)", "3/2"},
+		{"4^0.5", "2"},
+		{"gcd(12, 18)", "6"},
+		{"lcm(4611686018427387904, 3)", "13835058055282163712"},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateRat(context.Background(), mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got.RatString() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.src, got.RatString(), tt.want)
+		}
+	}
+	_, err := EvaluateRat(context.Background(), mustParse(t, "1/(1-1)"))
+	if !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("division by zero: %v", err)
+	}
+}
+
+func TestEvaluateRatInexact(t *testing.T) {
+	for _, src := range []string{"2^0.5", "pow(2, 0.5)", "pi", "2 * e", "sqrt(2)", "sin(1)", "2^100000"} {
+		if v, err := EvaluateRat(context.Background(), mustParse(t, src)); !errors.Is(err, ErrInexact) {
+			t.Errorf("%s = %v, %v, want ErrInexact", src, v, err)
+		}
+	}
+}
+
+func TestEvaluateBigFloat(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{Precision: PrecisionBigFloat, Prec: 200})
+	sqrt2 := new(big.Float).SetPrec(200).Sqrt(new(big.Float).SetPrec(200).SetInt64(2))
+	for _, src := range []string{"sqrt(2)", "2^0.5", "pow(2, 0.5)"} {
+		got, err := EvaluateBigFloat(ctx, mustParse(t, src))
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got.Cmp(sqrt2) != 0 {
+			t.Errorf("%s = %s, want %s", src, got.Text('g', 60), sqrt2.Text('g', 60))
+		}
+	}
+	got, err := EvaluateBigFloat(ctx, mustParse(t, "1/3 + 2^100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := got.Text('f', 20); s != "1267650600228229401496703205376.33333333333333333333" {
+		t.Errorf("1/3 + 2^100 = %s", s)
+	}
+}
+
+func TestEvaluateBigFloatConst(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{Precision: PrecisionBigFloat, Prec: 200})
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"pi", "3.14159265358979323846264338327950288419716939937510582097494"},
+		{"e", "2.71828182845904523536028747135266249775724709369995957496697"},
+		{"2 * pi", "6.28318530717958647692528676655900576839433879875021164194989"},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateBigFloat(ctx, mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if s := got.Text('f', 59); s != tt.want {
+			t.Errorf("%s = %s, want %s", tt.src, s, tt.want)
+		}
+	}
+	env := NewEnvironment(nil)
+	if err := env.RegConst("phi", 1.618033988749895); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.ParseExpression("phi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EvaluateBigFloat(WithEnvironment(ctx, env), expr); !errors.Is(err, ErrInexact) {
+		t.Errorf("float constant: got %v, want ErrInexact", err)
+	}
+}
+
+func TestEvaluateBigFloatInexact(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{Precision: PrecisionBigFloat, Prec: 200})
+	for _, src := range []string{"2^0.3", "pow(2, 0.3)", "sin(1)", "2^100000"} {
+		_, err := EvaluateBigFloat(ctx, mustParse(t, src))
+		if !errors.Is(err, ErrInexact) {
+			t.Errorf("%s: got %v, want ErrInexact", src, err)
+		}
+	}
+	if _, err := EvaluateBigFloat(ctx, mustParse(t, "round(2.5) + abs(-1)")); err != nil {
+		t.Errorf("rat functions: %v", err)
+	}
+}
+
+func TestEvaluateString(t *testing.T) {
+	tests := []struct {
+		opts   Options
+		src    string
+		digits int
+		want   string
+	}{
+		{Options{}, "1/4", -1, "0.25"},
+		{Options{Precision: PrecisionRat}, "1/3", -1, "1/3"},
+		{Options{Precision: PrecisionRat}, "1/3", 4, "0.3333"},
+		{Options{Precision: PrecisionBigFloat}, "1/3", 30, "0.333333333333333333333333333333"},
+		{Options{Precision: PrecisionDecimal}, "0.1+0.2", -1, "0.3"},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateString(WithOptions(context.Background(), tt.opts), mustParse(t, tt.src), tt.digits)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s (precision %d) = %s, want %s", tt.src, tt.opts.Precision, got, tt.want)
+		}
+	}
+}
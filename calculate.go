@@ -2,84 +2,406 @@ package mathastc
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math/big"
+	"strconv"
 )
 
-// Calculate 计算节点
+// Calculate 计算节点，异常时panic，需要error返回请使用Evaluate
 func Calculate(expr ExprNode, ctx context.Context) float64 {
+	v, err := Evaluate(ctx, expr)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Evaluate 计算节点，异常以error返回，出错的子表达式记录在EvalError中
+// 计算过程响应ctx的取消及超时，并受WithLimits设置的计算限制约束
+func Evaluate(ctx context.Context, expr ExprNode) (float64, error) {
+	ev := newEvaluator(ctx)
+	switch ev.st.opts.Precision {
+	case PrecisionBigFloat:
+		v, err := newNumEvaluator[*big.Float](ev, newBigFloatArith(ev.st.opts)).eval(expr)
+		if err != nil {
+			return 0, err
+		}
+		f, _ := v.Float64()
+		return f, nil
+	case PrecisionRat:
+		v, err := newNumEvaluator[*big.Rat](ev, ratArith{}).eval(expr)
+		if err != nil {
+			return 0, err
+		}
+		f, _ := v.Float64()
+		return f, nil
+	case PrecisionDecimal:
+		v, err := newNumEvaluator[Decimal](ev, decimalArith{policy: decimalPolicy(ev.ctx, ev.st.opts)}).eval(expr)
+		if err != nil {
+			return 0, err
+		}
+		return v.Float64(), nil
+	}
+	return ev.eval(expr)
+}
+
+// newEvaluator 创建计算过程，函数内部对参数的计算共享同一计算过程的限制及变量展开链
+func newEvaluator(ctx context.Context) *evaluator {
+	env := EnvironmentFrom(ctx)
+	if st, ok := ctx.Value(evalStateCtxKey{}).(*evalState); ok {
+		return &evaluator{ctx: ctx, env: env, st: st}
+	}
+	st := &evalState{limits: LimitsFrom(ctx), opts: OptionsFrom(ctx), done: ctx.Done()}
+	ctx = context.WithValue(ctx, evalStateCtxKey{}, st)
+	return &evaluator{ctx: ctx, env: env, st: st}
+}
+
+type evalStateCtxKey struct{}
+
+// evalState 单次计算的状态
+type evalState struct {
+	limits Limits
+	opts   Options
+	done   <-chan struct{}
+	nodes  int
+	depth  int
+	vars   []string           // 正在展开的变量
+	values map[string]float64 // Options.MemoVars开启时已计算的变量值
+}
+
+type evaluator struct {
+	ctx context.Context
+	env *Environment
+	st  *evalState
+}
+
+// enter 进入节点，检查取消及计算限制
+func (ev *evaluator) enter() error {
+	st := ev.st
+	select {
+	case <-st.done:
+		return ev.ctx.Err()
+	default:
+	}
+	st.nodes++
+	if st.limits.MaxNodes > 0 && st.nodes > st.limits.MaxNodes {
+		return &LimitError{Name: "node visits", Limit: st.limits.MaxNodes}
+	}
+	st.depth++
+	if st.limits.MaxDepth > 0 && st.depth > st.limits.MaxDepth {
+		st.depth--
+		return &LimitError{Name: "recursion depth", Limit: st.limits.MaxDepth}
+	}
+	return nil
+}
+
+func (ev *evaluator) eval(expr ExprNode) (float64, error) {
+	if err := ev.enter(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		ev.st.depth--
+	}()
 
-	var l, r float64
 	switch node := expr.(type) {
 
 	case OperatorExprNode:
-		l = Calculate(node.Lhs, ctx)
-		r = Calculate(node.Rhs, ctx)
-		return GetOperator(node.Op[0]).Result(l, r)
+		l, err := ev.eval(node.Lhs)
+		if err != nil {
+			return 0, err
+		}
+		operator, _ := ev.env.Operator(node.Op)
+		if s, ok := operator.(ShortCircuitOperator); ok {
+			if v, ok := s.ShortCircuit(l); ok {
+				return v, nil
+			}
+		}
+		r, err := ev.eval(node.Rhs)
+		if err != nil {
+			return 0, err
+		}
+		v, err := evalOperator(operator, node.Op, l, r)
+		if err != nil {
+			return 0, &EvalError{Expr: node, Err: err}
+		}
+		return ev.check(node, v)
+
+	case UnaryExprNode:
+		a, err := ev.eval(node.Operand)
+		if err != nil {
+			return 0, err
+		}
+		operator, _ := ev.env.Operator(node.Op)
+		v, err := evalUnary(operator, node.Op, a)
+		if err != nil {
+			return 0, &EvalError{Expr: node, Err: err}
+		}
+		return ev.check(node, v)
+
+	case ConditionalExprNode:
+		c, err := ev.eval(node.Cond)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return ev.eval(node.Then)
+		}
+		return ev.eval(node.Else)
 
 	case NumberExprNode:
-		return node.Val
+		return node.Val, nil
 
 	case ConstExprNode:
-		return node.Val
+		return node.Val, nil
+
+	case StringExprNode:
+		return 0, &EvalError{Expr: node, Err: &TypeError{Want: "number", Kinds: []ValueKind{StringKind}}}
 
 	case VariableExprNode:
-		val := node.Val
-		parameter, err := GetCtxParameter(ctx)
+		v, err := ev.evalVariable(node.Val)
 		if err != nil {
-			log.Panicf("no parameter found, err = %v\n", err)
+			return 0, &EvalError{Expr: node, Err: err}
 		}
+		return v, nil
 
-		value, ok := parameter.Vars[val]
-		if !ok {
-			log.Panicf("no parameter value found for %s\n", val)
+	case FunCallerExprNode:
+		def, _ := ev.env.Func(node.Name)
+		v, err := evalFunc(ev.ctx, def, node.Name, node.Arg)
+		if err != nil {
+			// 参数计算异常已记录出错节点，直接向上传递
+			var evalErr *EvalError
+			if errors.As(err, &evalErr) {
+				return 0, err
+			}
+			return 0, &EvalError{Expr: node, Err: err}
 		}
+		return ev.check(node, v)
+	}
 
-		switch t := value.(type) {
-		case string:
-			expression, err2 := ParseExpression(t)
-			if err2 != nil {
-				log.Panicf("Convert to expression node exception, err = %s\n", t)
-			}
-			return Calculate(expression, ctx)
-		case ExprNode:
-			return Calculate(t, ctx)
-		case int:
-			return float64(t)
-		case int8:
-			return float64(t)
-		case int64:
-			return float64(t)
-		case int16:
-			return float64(t)
-		case int32:
-			return float64(t)
-		case uint:
-			return float64(t)
-		case uint8:
-			return float64(t)
-		case uint16:
-			return float64(t)
-		case uint32:
-			return float64(t)
-		case uint64:
-			return float64(t)
-		case float32:
-			return float64(t)
-		case float64:
-			return t
-		default:
-			log.Panicln("unknown expr type")
+	return 0.0, nil
+}
+
+// check 严格模式下校验运算结果
+func (ev *evaluator) check(node ExprNode, v float64) (float64, error) {
+	if ev.st.opts.Strict && !isFinite(v) {
+		return 0, &EvalError{Expr: node, Err: ErrNotFinite}
+	}
+	return v, nil
+}
+
+// evalOperator 执行操作符运算，Result的panic转换为OperatorError
+func evalOperator(operator OperatorItem, op string, l, r float64) (v float64, err error) {
+	if operator == nil {
+		return 0, &OperatorError{Op: op, Lhs: l, Rhs: r, Err: ErrUndefinedOperator}
+	}
+	if e, ok := operator.(OperatorEvaluator); ok {
+		return e.Evaluate(l, r)
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = &OperatorError{Op: op, Lhs: l, Rhs: r, Err: recoverError(e)}
 		}
+	}()
+	return operator.Result(l, r), nil
+}
 
-	case FunCallerExprNode:
-		def := GetDefFunc(node.Name)
-		return def.Calculate(ctx, node.Arg...)
+// evalUnary 执行一元操作符运算，UnaryResult的panic转换为OperatorError
+func evalUnary(operator OperatorItem, op string, a float64) (v float64, err error) {
+	u, ok := operator.(UnaryOperatorItem)
+	if !ok {
+		return 0, &OperatorError{Op: op, Rhs: a, Err: ErrUndefinedOperator}
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = &OperatorError{Op: op, Rhs: a, Err: recoverError(e)}
+		}
+	}()
+	return u.UnaryResult(a), nil
+}
+
+// evalFunc 执行函数运算，Calculate的panic转换为FuncError
+func evalFunc(ctx context.Context, def DefFunc, name string, args []ExprNode) (v float64, err error) {
+	if def == nil {
+		return 0, &FuncError{Name: name, Err: ErrUndefinedFunc}
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = recoverError(e)
+		}
+		var evalErr *EvalError
+		if err != nil && !errors.As(err, &evalErr) {
+			err = &FuncError{Name: name, Err: err}
+		}
+	}()
+	if e, ok := def.(EvaluateFunc); ok {
+		return e.Evaluate(ctx, args...)
+	}
+	return def.Calculate(ctx, args...), nil
+}
+
+// evalVariable 计算变量值，字符串及表达式变量在展开链中出现循环时返回CycleError
+func (ev *evaluator) evalVariable(name string) (float64, error) {
+	value, err := ev.resolve(name)
+	if err != nil {
+		return 0, err
+	}
+	expression, err := ev.variableExpr(name, value)
+	if err != nil {
+		return 0, err
+	}
+	if expression == nil {
+		if v, ok := toFloat64(value); ok {
+			return v, nil
+		}
+		return 0, &VariableError{Name: name, Value: value, Err: ErrUnknownValueType}
 	}
 
-	return 0.0
+	st := ev.st
+	if v, ok := st.values[name]; ok {
+		return v, nil
+	}
+	if err := ev.pushVar(name); err != nil {
+		return 0, err
+	}
+	defer ev.popVar()
+
+	v, err := ev.eval(expression)
+	if err != nil {
+		return 0, variableError(name, value, expression, err)
+	}
+	if st.opts.MemoVars {
+		if st.values == nil {
+			st.values = map[string]float64{}
+		}
+		st.values[name] = v
+	}
+	return v, nil
+}
+
+// variableExpr 字符串及表达式变量转换为表达式，其他类型的变量返回nil
+func (ev *evaluator) variableExpr(name string, value any) (ExprNode, error) {
+	switch t := value.(type) {
+	case string:
+		parameter, _ := GetCtxParameter(ev.ctx)
+		expression, err := parameter.parseVar(ev.env, name, t)
+		if err != nil {
+			return nil, &VariableError{Name: name, Value: t, Err: err}
+		}
+		return expression, nil
+	case ExprNode:
+		return t, nil
+	}
+	return nil, nil
+}
+
+// pushVar 进入变量展开，检查循环引用及展开深度
+func (ev *evaluator) pushVar(name string) error {
+	st := ev.st
+	for i, v := range st.vars {
+		if v == name {
+			chain := append(append([]string{}, st.vars[i:]...), name)
+			return &CycleError{Chain: chain}
+		}
+	}
+	if st.limits.MaxVarDepth > 0 && len(st.vars) >= st.limits.MaxVarDepth {
+		return &LimitError{Name: "variable expansion depth", Limit: st.limits.MaxVarDepth}
+	}
+	st.vars = append(st.vars, name)
+	return nil
 }
 
+func (ev *evaluator) popVar() {
+	ev.st.vars = ev.st.vars[:len(ev.st.vars)-1]
+}
+
+// variableError 变量展开后的计算异常
+func variableError(name string, value any, expression ExprNode, err error) error {
+	if s, ok := value.(string); ok {
+		return &VariableError{Name: name, Value: s, Err: err}
+	}
+	return &VariableError{Name: name, Value: expression.ToStr(), Err: err}
+}
+
+func (ev *evaluator) resolve(name string) (any, error) {
+	return resolveVariable(ev.ctx, ev.st.opts, name)
+}
+
+// resolveVariable 获取变量值，优先使用Options.Resolver，未设置时使用上下文中的Parameter
+func resolveVariable(ctx context.Context, opts Options, name string) (any, error) {
+	if opts.Resolver != nil {
+		return opts.Resolver.Resolve(ctx, name)
+	}
+	parameter, err := GetCtxParameter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parameter.Resolve(ctx, name)
+}
+
+// toFloat64 转换Go数值类型
+func toFloat64(value any) (float64, bool) {
+	switch t := value.(type) {
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case float32:
+		return float64(t), true
+	case float64:
+		return t, true
+	case bool:
+		return boolResult(t), true
+	case *big.Rat:
+		f, _ := t.Float64()
+		return f, true
+	case *big.Float:
+		f, _ := t.Float64()
+		return f, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(t).Float64()
+		return f, true
+	case Decimal:
+		return t.Float64(), true
+	case Value:
+		if t.kind == BoolKind {
+			return float64(t.i), true
+		}
+		return t.Float()
+	}
+	return 0, false
+}
+
+// inlineExprStr 打印变量绑定的表达式，非原子表达式加括号
+func inlineExprStr(expr ExprNode, ctx context.Context) string {
+	s := ToExprStr(expr, ctx)
+	env := EnvironmentFrom(ctx)
+	p := &printer{env: env, legacy: env.LegacyPrecedence()}
+	if p.precedence(expr) != atomPrecedence {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// exprStrCtxKey ToExprStr正在展开的变量
+type exprStrCtxKey struct{}
+
 // ToExprStr 打印节点
 func ToExprStr(expr ExprNode, ctx context.Context) string {
 
@@ -87,25 +409,58 @@ func ToExprStr(expr ExprNode, ctx context.Context) string {
 	switch node := expr.(type) {
 
 	case OperatorExprNode:
+		env := EnvironmentFrom(ctx)
+		p := &printer{env: env, legacy: env.LegacyPrecedence()}
 		l = ToExprStr(node.Lhs, ctx)
 		r = ToExprStr(node.Rhs, ctx)
-		operator := GetOperator(node.Op[0])
+		if !isPrefixOperand(node.Lhs) && p.needParens(node, node.Lhs, false) {
+			l = "(" + l + ")"
+		}
+		if isPrefixOperand(node.Lhs) && p.precedence(node.Rhs) != atomPrecedence ||
+			!isPrefixOperand(node.Lhs) && p.needParens(node, node.Rhs, true) {
+			r = "(" + r + ")"
+		}
+		operator, _ := env.Operator(node.Op)
 		if node.Flag {
 			return "(" + operator.ToExprStr(l, r) + ")"
 		}
 		return operator.ToExprStr(l, r)
 
+	case UnaryExprNode:
+		env := EnvironmentFrom(ctx)
+		p := &printer{env: env, legacy: env.LegacyPrecedence()}
+		operand := ToExprStr(node.Operand, ctx)
+		if p.unaryParens(node.Operand) {
+			operand = "(" + operand + ")"
+		}
+		operator, _ := env.Operator(node.Op)
+		if u, ok := operator.(UnaryOperatorItem); ok {
+			return u.ToUnaryExprStr(operand)
+		}
+		return node.Op + operand
+
+	case ConditionalExprNode:
+		cond := ToExprStr(node.Cond, ctx)
+		if _, ok := node.Cond.(ConditionalExprNode); ok {
+			cond = "(" + cond + ")"
+		}
+		return cond + " ? " + ToExprStr(node.Then, ctx) + " : " + ToExprStr(node.Else, ctx)
+
 	case NumberExprNode:
 		return node.Str
 
 	case ConstExprNode:
 		return node.Str
 
+	case StringExprNode:
+		return strconv.Quote(node.Val)
+
 	case VariableExprNode:
 		val := node.Val
+		// 未设置Parameter时输出变量名
 		parameter, err := GetCtxParameter(ctx)
 		if err != nil {
-			log.Panicf("no parameter found, err = %v\n", err)
+			return val
 		}
 
 		value, ok := parameter.Vars[val]
@@ -113,15 +468,22 @@ func ToExprStr(expr ExprNode, ctx context.Context) string {
 			return val
 		}
 
+		// 循环引用的变量不再展开
+		chain, _ := ctx.Value(exprStrCtxKey{}).([]string)
+		if containsStr(chain, val) {
+			return val
+		}
+		inner := context.WithValue(ctx, exprStrCtxKey{}, append(chain[:len(chain):len(chain)], val))
+
 		switch t := value.(type) {
 		case string:
-			expression, err2 := ParseExpression(t)
+			expression, err2 := parameter.parseVar(EnvironmentFrom(ctx), val, t)
 			if err2 != nil {
 				return val
 			}
-			return ToExprStr(expression, ctx)
+			return inlineExprStr(expression, inner)
 		case ExprNode:
-			return ToExprStr(t, ctx)
+			return inlineExprStr(t, inner)
 		case int, int8, int64, int16, int32, uint, uint8, uint16, uint32, uint64:
 			return fmt.Sprintf("%d", t)
 		case float32, float64:
@@ -131,7 +493,7 @@ func ToExprStr(expr ExprNode, ctx context.Context) string {
 		}
 
 	case FunCallerExprNode:
-		def := GetDefFunc(node.Name)
+		def, _ := EnvironmentFrom(ctx).Func(node.Name)
 		return def.ToExprStr(ctx, node.Arg...)
 	}
 
@@ -0,0 +1,95 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func mustParse(t testing.TB, s string) ExprNode {
+	t.Helper()
+	expr, err := ParseExpression(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return expr
+}
+
+func varsCtx(vars map[string]any) context.Context {
+	return WithParameter(context.Background(), NewParameter(vars, nil))
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := varsCtx(map[string]any{"x": 3, "y": 0.5, "s": "x*2"})
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1+2*3", 7},
+		{"(1+2)*3", 9},
+		{"x*y", 1.5},
+		{"s+1", 7},
+		{"7%3", 1},
+		{"2^3^2", 512},
+		{"-2^2", -4},
+		{"x > 2 ? 10 : 20", 10},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(ctx, mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	ctx := varsCtx(map[string]any{"bad": "1+", "a": "b", "b": "a"})
+
+	_, err := Evaluate(ctx, mustParse(t, "1/(2-2)"))
+	var opErr *OperatorError
+	if !errors.Is(err, ErrDivisionByZero) || !errors.As(err, &opErr) || opErr.Op != "/" {
+		t.Errorf("division by zero: %v", err)
+	}
+
+	_, err = Evaluate(ctx, mustParse(t, "1 + missing"))
+	var unbound *UnboundVariableError
+	if !errors.As(err, &unbound) || unbound.Name != "missing" {
+		t.Errorf("unbound variable: %v", err)
+	}
+	var evalErr *EvalError
+	if !errors.As(err, &evalErr) || evalErr.Span() != (Span{4, 11}) {
+		t.Errorf("eval error span: %v", err)
+	}
+
+	_, err = Evaluate(ctx, mustParse(t, "bad*2"))
+	var varErr *VariableError
+	if !errors.As(err, &varErr) || varErr.Name != "bad" {
+		t.Errorf("invalid string variable: %v", err)
+	}
+
+	_, err = Evaluate(ctx, mustParse(t, "a"))
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Errorf("cycle: %v", err)
+	}
+
+	_, err = Evaluate(context.Background(), mustParse(t, "x"))
+	if !errors.Is(err, ErrNoParameter) {
+		t.Errorf("no parameter: %v", err)
+	}
+}
+
+func TestToExprStrWithoutParameter(t *testing.T) {
+	if got := ToExprStr(mustParse(t, "x + 1"), context.Background()); got != "x + 1" {
+		t.Errorf("ToExprStr = %q", got)
+	}
+	ctx := varsCtx(map[string]any{"x": "y*2"})
+	if got := ToExprStr(mustParse(t, "x + 1"), ctx); got != "(y * 2) + 1" {
+		t.Errorf("ToExprStr with expression variable = %q", got)
+	}
+}
@@ -0,0 +1,289 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+)
+
+// ComplexOperator 复数运算，EvaluateComplex优先使用，未实现时仅支持实数操作数，以float64计算
+type ComplexOperator interface {
+	ComplexResult(a complex128, b complex128) (complex128, error)
+}
+
+// ComplexFunc 以复数参数计算，EvaluateComplex优先使用，未实现时仅支持实数参数，以float64计算
+type ComplexFunc interface {
+	CallComplex(args []complex128) (complex128, error)
+}
+
+// CalculateComplex 以复数计算，异常时panic，需要error返回请使用EvaluateComplex
+func CalculateComplex(expr ExprNode, ctx context.Context) complex128 {
+	v, err := EvaluateComplex(ctx, expr)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// EvaluateComplex 以复数计算，虚数单位名称由Options.ImagUnit指定，为空时i和j均表示虚数单位
+// 变量值可以是complex128、complex64及数值类型
+// 虚数单位不是常量，解析结果中仍为VariableExprNode，仅在变量未绑定值时取值为虚数单位，绑定同名变量时以变量值为准；
+// ToExprStr、ToLaTex、Variables及Diff均将其视为普通变量，与已有变量名冲突时请通过Options.ImagUnit指定其他名称
+func EvaluateComplex(ctx context.Context, expr ExprNode) (complex128, error) {
+	ev := newEvaluator(ctx)
+	return newNumEvaluator[complex128](ev, complexArith{opts: ev.st.opts}).eval(expr)
+}
+
+// FormatComplex 输出复数，如 3+4i、-2i，虚部为0时只输出实部
+func FormatComplex(c complex128) string {
+	re, im := real(c), imag(c)
+	if cmplx.IsNaN(c) {
+		return "NaN"
+	}
+	if im == 0 {
+		return Float64ToStr(re)
+	}
+	s := Float64ToStr(im)
+	if re == 0 {
+		return s + "i"
+	}
+	if !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "+") {
+		s = "+" + s
+	}
+	return Float64ToStr(re) + s + "i"
+}
+
+// complexArith 复数计算
+type complexArith struct {
+	opts Options
+}
+
+func (ar complexArith) literal(n NumberExprNode) (complex128, error) {
+	return complex(n.Val, 0), nil
+}
+
+func (ar complexArith) convert(value any) (complex128, bool) {
+	switch t := value.(type) {
+	case complex128:
+		return t, true
+	case complex64:
+		return complex128(t), true
+	}
+	return 0, false
+}
+
+func (ar complexArith) fromFloat(f float64) (complex128, error) {
+	return complex(f, 0), nil
+}
+
+func (ar complexArith) toFloat(v complex128) float64 {
+	return real(v)
+}
+
+func (ar complexArith) truth(v complex128) (bool, error) {
+	return v != 0, nil
+}
+
+// unbound 未绑定值的虚数单位
+func (ar complexArith) unbound(name string) (complex128, bool) {
+	if name == ar.opts.ImagUnit || (ar.opts.ImagUnit == "" && (name == "i" || name == "j")) {
+		return complex(0, 1), true
+	}
+	return 0, false
+}
+
+func (ar complexArith) shortCircuit(operator OperatorItem, op string, a complex128) (complex128, bool, error) {
+	switch operator.(type) {
+	case *And:
+		if a == 0 {
+			return 0, true, nil
+		}
+	case *Or:
+		if a != 0 {
+			return 1, true, nil
+		}
+	default:
+		if s, ok := operator.(ShortCircuitOperator); ok && imag(a) == 0 {
+			if v, ok := s.ShortCircuit(real(a)); ok {
+				return complex(v, 0), true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+func (ar complexArith) operator(operator OperatorItem, op string, a complex128, b complex128) (complex128, bool, error) {
+	if o, ok := operator.(ComplexOperator); ok {
+		v, err := o.ComplexResult(a, b)
+		if err != nil {
+			return v, true, &OperatorError{Op: op, Lhs: real(a), Rhs: real(b), Err: err}
+		}
+		return v, true, nil
+	}
+	switch operator.(type) {
+	case *Equal, *NotEqual, *And, *Or, *Not:
+		cmp := 0
+		if a != b {
+			cmp = 1
+		}
+		v, _ := compareResult(operator, cmp, a != 0, b != 0)
+		return complexBool(v), true, nil
+	}
+	// 比较及其他运算仅支持实数
+	if imag(a) != 0 || imag(b) != 0 {
+		return 0, true, errors.New(fmt.Sprintf("operator `%s` does not support complex operands %s and %s",
+			op, FormatComplex(a), FormatComplex(b)))
+	}
+	return 0, false, nil
+}
+
+func (ar complexArith) unary(operator OperatorItem, op string, a complex128) (complex128, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		// 0-a避免虚部为-0，-1的平方根等按主值计算
+		return 0 - a, true, nil
+	case *Plus:
+		return a, true, nil
+	case *Not:
+		return complexBool(a == 0), true, nil
+	}
+	if imag(a) != 0 {
+		return 0, true, errors.New(fmt.Sprintf("operator `%s` does not support complex operand %s", op, FormatComplex(a)))
+	}
+	return 0, false, nil
+}
+
+func (ar complexArith) call(def DefFunc, name string, args []complex128) (complex128, bool, error) {
+	if fn := complexFunc(def); fn != nil {
+		kind := funcAngle(def)
+		for i, a := range args {
+			if kind == angleArg {
+				a = complex(ar.opts.AngleUnit.ToRadian(real(a)), ar.opts.AngleUnit.ToRadian(imag(a)))
+			}
+			// 实数参数的虚部统一为+0，避免cmplx函数在分支切割上取到另一侧的值
+			if imag(a) == 0 {
+				a = complex(real(a), 0)
+			}
+			args[i] = a
+		}
+		v, err := fn(args)
+		if err == nil && kind == angleResult {
+			v = complex(ar.opts.AngleUnit.FromRadian(real(v)), ar.opts.AngleUnit.FromRadian(imag(v)))
+		}
+		return v, true, err
+	}
+	for _, a := range args {
+		if imag(a) != 0 {
+			return 0, true, errors.New(fmt.Sprintf("complex argument %s is not supported", FormatComplex(a)))
+		}
+	}
+	return 0, false, nil
+}
+
+func complexBool(b bool) complex128 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// complexFunc 获取函数的复数实现
+func complexFunc(def DefFunc) func(args []complex128) (complex128, error) {
+	switch t := def.(type) {
+	case ComplexFunc:
+		return t.CallComplex
+	case *stdFunc:
+		return t.complex
+	case *stdDiffFunc:
+		return t.complex
+	}
+	return nil
+}
+
+// maxComplexIntPow 整数次幂以乘法计算的最大指数
+const maxComplexIntPow = 1024
+
+// complexPow 实数结果以math.Pow计算，整数次幂以乘法计算，其他以cmplx.Pow计算主值
+func complexPow(a complex128, b complex128) complex128 {
+	if imag(b) == 0 {
+		n := real(b)
+		if imag(a) == 0 && (real(a) >= 0 || n == math.Trunc(n)) {
+			return complex(math.Pow(real(a), n), 0)
+		}
+		// 避免cmplx.Pow经由对数计算引入误差，如 i^2 = -1
+		if n == math.Trunc(n) && math.Abs(n) <= maxComplexIntPow {
+			return complexIntPow(a, int(n))
+		}
+	}
+	if imag(a) == 0 {
+		a = complex(real(a), 0)
+	}
+	return cmplx.Pow(a, b)
+}
+
+// complexIntPow 整数次幂，负指数取倒数
+func complexIntPow(a complex128, n int) complex128 {
+	if n < 0 {
+		return 1 / complexIntPow(a, -n)
+	}
+	r := complex(1, 0)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r *= a
+		}
+		a *= a
+	}
+	return r
+}
+
+// complexUnary 单参数复数函数
+func complexUnary(fn func(complex128) complex128) func(args []complex128) (complex128, error) {
+	return func(args []complex128) (complex128, error) {
+		return fn(args[0]), nil
+	}
+}
+
+func complexLog2(z complex128) complex128 {
+	return cmplx.Log(z) / math.Ln2
+}
+
+func complexLogFunc(args []complex128) (complex128, error) {
+	if len(args) == 1 {
+		return cmplx.Log(args[0]), nil
+	}
+	return cmplx.Log(args[0]) / cmplx.Log(args[1]), nil
+}
+
+func complexPowFunc(args []complex128) (complex128, error) {
+	return complexPow(args[0], args[1]), nil
+}
+
+func complexAbs(args []complex128) (complex128, error) {
+	return complex(cmplx.Abs(args[0]), 0), nil
+}
+
+func complexRe(args []complex128) (complex128, error) {
+	return complex(real(args[0]), 0), nil
+}
+
+func complexIm(args []complex128) (complex128, error) {
+	return complex(imag(args[0]), 0), nil
+}
+
+func complexArg(args []complex128) (complex128, error) {
+	return complex(cmplx.Phase(args[0]), 0), nil
+}
+
+// withComplex 设置标准库函数的复数实现
+func withComplex(fn func(args []complex128) (complex128, error), f DefFunc) DefFunc {
+	switch t := f.(type) {
+	case *stdFunc:
+		t.complex = fn
+	case *stdDiffFunc:
+		t.complex = fn
+	}
+	return f
+}
@@ -0,0 +1,89 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestEvaluateComplex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want complex128
+	}{
+		{"i*i", -1},
+		{"i^2", -1},
+		{"2*i^2", -2},
+		{"(1+i)^2", 2i},
+		{"(1+i)^-1", 0.5 - 0.5i},
+		{"i^4", 1},
+		{"sqrt(-1)", 1i},
+		{"sqrt(-4)", 2i},
+		{"(3+4*i)*(3-4*i)", 25},
+		{"abs(3+4*i)", 5},
+		{"(-8)^(1/3)", cmplx.Pow(-8, complex(1.0/3, 0))},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateComplex(context.Background(), mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.src, FormatComplex(got), FormatComplex(tt.want))
+		}
+	}
+}
+
+func TestEvaluateComplexEuler(t *testing.T) {
+	got, err := EvaluateComplex(context.Background(), mustParse(t, "e^(i*pi)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmplx.Abs(got+1) > 1e-15 {
+		t.Errorf("e^(i*pi) = %s", FormatComplex(got))
+	}
+}
+
+func TestEvaluateComplexImagUnit(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{ImagUnit: "j"})
+	ctx = WithParameter(ctx, NewParameter(map[string]any{"i": 2}, nil))
+	got, err := EvaluateComplex(ctx, mustParse(t, "i+j"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 2+1i {
+		t.Errorf("i+j = %s", FormatComplex(got))
+	}
+}
+
+// 虚数单位是未绑定值的变量，绑定同名变量时以变量值为准，其他处理中视为普通变量
+func TestEvaluateComplexImagUnitIsVariable(t *testing.T) {
+	expr := mustParse(t, "3 + 4*i")
+	ctx := WithParameter(context.Background(), NewParameter(map[string]any{"i": 2}, nil))
+	if got, err := EvaluateComplex(ctx, expr); err != nil || got != 11 {
+		t.Errorf("bound i: 3 + 4*i = %s, %v", FormatComplex(got), err)
+	}
+	if vars := Variables(expr); len(vars) != 1 || vars[0].Name != "i" {
+		t.Errorf("Variables = %v", vars)
+	}
+	if s := ToLaTex(expr, context.Background()); s != "3 + 4 \\times i" {
+		t.Errorf("ToLaTex = %s", s)
+	}
+	d, err := Diff(context.Background(), mustParse(t, "x * i"), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := EvaluateComplex(context.Background(), d); err != nil || got != 1i {
+		t.Errorf("d(x*i)/dx = %s = %s, %v", Format(d), FormatComplex(got), err)
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	for c, want := range map[complex128]string{3 + 4i: "3+4i", -2i: "-2i", 1.5: "1.5", 1 - 1i: "1-1i", complex(math.NaN(), 0): "NaN"} {
+		if got := FormatComplex(c); got != want {
+			t.Errorf("FormatComplex(%v) = %s, want %s", c, got, want)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// 定点小数的舍入方式
+const (
+	RoundHalfUp   = big.ToNearestAway // 四舍五入，.5远离零
+	RoundHalfEven = big.ToNearestEven // 四舍六入五成双
+	RoundDown     = big.ToZero        // 截断
+	RoundUp       = big.AwayFromZero  // 远离零进位
+	RoundFloor    = big.ToNegativeInf // 向下取整
+	RoundCeiling  = big.ToPositiveInf // 向上取整
+)
+
+// DefaultScale PrecisionDecimal默认保留的小数位数
+const DefaultScale = 18
+
+// DecimalPolicy 定点小数计算规则，加减乘及取模精确计算，除法等无法精确表示的结果按Scale及Rounding舍入
+type DecimalPolicy struct {
+	Scale    *int             // 小数位数，为nil时使用DefaultScale，为0时结果舍入为整数
+	Rounding big.RoundingMode // 舍入方式
+}
+
+// NewDecimalPolicy 创建指定小数位数及舍入方式的计算规则
+func NewDecimalPolicy(scale int, rounding big.RoundingMode) DecimalPolicy {
+	return DecimalPolicy{Scale: &scale, Rounding: rounding}
+}
+
+func (p DecimalPolicy) scale() int {
+	if p.Scale == nil {
+		return DefaultScale
+	}
+	return maxInt(*p.Scale, 0)
+}
+
+// DecimalOperator 定点小数运算，PrecisionDecimal模式优先使用，未实现时以float64计算
+type DecimalOperator interface {
+	DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error)
+}
+
+// DecimalFunc 以定点小数参数计算，PrecisionDecimal模式优先使用，其次使用RatFunc
+type DecimalFunc interface {
+	CallDecimal(args []Decimal, policy DecimalPolicy) (Decimal, error)
+}
+
+// Decimal 定点小数，值为 coef × 10^-scale，零值为0
+type Decimal struct {
+	coef  *big.Int
+	scale int
+}
+
+var bigTen = big.NewInt(10)
+
+// pow10 10的n次幂
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// NewDecimal 创建定点小数 unscaled × 10^-scale
+func NewDecimal(unscaled int64, scale int) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+	return Decimal{coef: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal 解析十进制小数，支持指数形式，保留书写的小数位数，如 1.50 的小数位数为2
+func ParseDecimal(s string) (Decimal, error) {
+	src := s
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, errors.New(fmt.Sprintf("invalid decimal `%s`", src))
+		}
+		s, exp = s[:i], e
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	coef, ok := new(big.Int).SetString(strings.TrimPrefix(s, "+"), 10)
+	if !ok {
+		return Decimal{}, errors.New(fmt.Sprintf("invalid decimal `%s`", src))
+	}
+	scale -= exp
+	if scale < 0 {
+		return Decimal{coef: coef.Mul(coef, pow10(-scale))}, nil
+	}
+	return Decimal{coef: coef, scale: scale}, nil
+}
+
+// DecimalFromFloat 以可还原的最短十进制形式转换float64，0.1转换为0.1
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if !isFinite(f) {
+		return Decimal{}, ErrNotFinite
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// DecimalFromRat 按小数位数及舍入方式转换有理数
+func DecimalFromRat(r *big.Rat, scale int, mode big.RoundingMode) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+	num := new(big.Int).Mul(r.Num(), pow10(scale))
+	return Decimal{coef: roundQuo(num, r.Denom(), mode), scale: scale}
+}
+
+// exactDecimal 有限小数精确转换，无限小数或小数位数超过maxScale时按maxScale舍入
+func exactDecimal(r *big.Rat, maxScale int, mode big.RoundingMode) Decimal {
+	if scale, ok := decimalPlaces(r); ok && scale <= maxScale {
+		return DecimalFromRat(r, scale, mode)
+	}
+	return DecimalFromRat(r, maxScale, mode)
+}
+
+// decimalPlaces 有理数为有限小数时返回小数位数，分母仅含因子2和5时为有限小数
+func decimalPlaces(r *big.Rat) (int, bool) {
+	d := new(big.Int).Set(r.Denom())
+	two, five := big.NewInt(2), big.NewInt(5)
+	m := new(big.Int)
+	n2, n5 := 0, 0
+	for m.Mod(d, two).Sign() == 0 {
+		d.Quo(d, two)
+		n2++
+	}
+	for m.Mod(d, five).Sign() == 0 {
+		d.Quo(d, five)
+		n5++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return maxInt(n2, n5), true
+}
+
+// roundQuo 按舍入方式计算 num / den
+func roundQuo(num *big.Int, den *big.Int, mode big.RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// 结果的符号，截断后q可能为0
+	sign := num.Sign() * den.Sign()
+	// 余数的两倍与除数比较，判断是否超过一半
+	half := new(big.Int).Abs(r)
+	cmp := half.Lsh(half, 1).Cmp(new(big.Int).Abs(den))
+	inc := false
+	switch mode {
+	case big.ToZero:
+	case big.AwayFromZero:
+		inc = true
+	case big.ToNearestAway:
+		inc = cmp >= 0
+	case big.ToNearestEven:
+		inc = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	case big.ToNegativeInf:
+		inc = sign < 0
+	case big.ToPositiveInf:
+		inc = sign > 0
+	}
+	if inc {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
+
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// Scale 小数位数
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign 符号，-1、0或1
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Rat 转换为有理数
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10(d.scale))
+}
+
+// Float64 转换为最接近的float64
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String 输出小数，保留全部小数位数
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(s) <= d.scale {
+			s = strings.Repeat("0", d.scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+	}
+	if d.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// rescale 扩展到更多的小数位数
+func (d Decimal) rescale(scale int) *big.Int {
+	if scale <= d.scale {
+		return d.int()
+	}
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+// Cmp 比较大小
+func (d Decimal) Cmp(e Decimal) int {
+	scale := maxInt(d.scale, e.scale)
+	return d.rescale(scale).Cmp(e.rescale(scale))
+}
+
+// Neg 取反
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs 绝对值
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Add 相加，结果的小数位数为两者中较大的一个
+func (d Decimal) Add(e Decimal) Decimal {
+	scale := maxInt(d.scale, e.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescale(scale), e.rescale(scale)), scale: scale}
+}
+
+// Sub 相减
+func (d Decimal) Sub(e Decimal) Decimal {
+	scale := maxInt(d.scale, e.scale)
+	return Decimal{coef: new(big.Int).Sub(d.rescale(scale), e.rescale(scale)), scale: scale}
+}
+
+// Mul 相乘，结果的小数位数为两者之和
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Quo 相除，结果按小数位数及舍入方式舍入
+func (d Decimal) Quo(e Decimal, scale int, mode big.RoundingMode) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	return DecimalFromRat(new(big.Rat).Quo(d.Rat(), e.Rat()), scale, mode), nil
+}
+
+// Mod 截断取余 d - e*trunc(d/e)，结果符号与被除数一致
+func (d Decimal) Mod(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	scale := maxInt(d.scale, e.scale)
+	a, b := d.rescale(scale), e.rescale(scale)
+	return Decimal{coef: new(big.Int).Rem(a, b), scale: scale}, nil
+}
+
+// Round 按小数位数及舍入方式舍入，小数位数不足时补0
+func (d Decimal) Round(scale int, mode big.RoundingMode) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+	if scale >= d.scale {
+		return Decimal{coef: d.rescale(scale), scale: scale}
+	}
+	return Decimal{coef: roundQuo(d.int(), pow10(d.scale-scale), mode), scale: scale}
+}
+
+// Pow 整数次幂，负数次幂按小数位数及舍入方式舍入
+func (d Decimal) Pow(n int64, scale int, mode big.RoundingMode) (Decimal, error) {
+	if n < 0 {
+		if d.Sign() == 0 {
+			return Decimal{}, ErrDivisionByZero
+		}
+		p, err := d.Pow(-n, scale, mode)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return NewDecimal(1, 0).Quo(p, scale, mode)
+	}
+	if n > maxExactExp {
+		return Decimal{}, errors.New(fmt.Sprintf("exponent %d is out of range", n))
+	}
+	coef := new(big.Int).Exp(d.int(), big.NewInt(n), nil)
+	return Decimal{coef: coef, scale: d.scale * int(n)}, nil
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// EvaluateDecimal 以定点小数计算，计算规则由Options.Decimal指定，上下文中的Parameter设置了Decimal时优先使用
+func EvaluateDecimal(ctx context.Context, expr ExprNode) (Decimal, error) {
+	ev := newEvaluator(ctx)
+	return newNumEvaluator[Decimal](ev, decimalArith{policy: decimalPolicy(ev.ctx, ev.st.opts)}).eval(expr)
+}
+
+// decimalPolicy 获取定点小数计算规则
+func decimalPolicy(ctx context.Context, opts Options) DecimalPolicy {
+	if parameter, err := GetCtxParameter(ctx); err == nil && parameter.Decimal != nil {
+		return *parameter.Decimal
+	}
+	return opts.Decimal
+}
+
+// decimalArith 定点小数计算
+type decimalArith struct {
+	policy DecimalPolicy
+}
+
+func (ar decimalArith) literal(n NumberExprNode) (Decimal, error) {
+	if n.Str != "" {
+		if d, err := ParseDecimal(n.Str); err == nil {
+			return d, nil
+		}
+	}
+	return ar.fromFloat(n.Val)
+}
+
+func (ar decimalArith) convert(value any) (Decimal, bool) {
+	switch t := value.(type) {
+	case Decimal:
+		return t, true
+	case *big.Rat:
+		return ar.fromRat(t), true
+	case *big.Int:
+		return Decimal{coef: new(big.Int).Set(t)}, true
+	case *big.Float:
+		if !t.IsInf() {
+			r, _ := t.Rat(nil)
+			return ar.fromRat(r), true
+		}
+	}
+	return Decimal{}, false
+}
+
+// fromRat 有限小数精确转换，否则按计算规则舍入
+func (ar decimalArith) fromRat(r *big.Rat) Decimal {
+	return exactDecimal(r, ar.policy.scale(), ar.policy.Rounding)
+}
+
+func (ar decimalArith) fromFloat(f float64) (Decimal, error) {
+	d, err := DecimalFromFloat(f)
+	if err != nil {
+		return d, err
+	}
+	if d.scale > ar.policy.scale() {
+		return d.Round(ar.policy.scale(), ar.policy.Rounding), nil
+	}
+	return d, nil
+}
+
+func (ar decimalArith) toFloat(v Decimal) float64 {
+	return v.Float64()
+}
+
+func (ar decimalArith) truth(v Decimal) (bool, error) {
+	return v.Sign() != 0, nil
+}
+
+func (ar decimalArith) operator(operator OperatorItem, op string, a Decimal, b Decimal) (Decimal, bool, error) {
+	if o, ok := operator.(DecimalOperator); ok {
+		v, err := o.DecimalResult(a, b, ar.policy)
+		if err != nil {
+			return v, true, &OperatorError{Op: op, Lhs: a.Float64(), Rhs: b.Float64(), Err: err}
+		}
+		return v, true, nil
+	}
+	if v, ok := compareResult(operator, a.Cmp(b), a.Sign() != 0, b.Sign() != 0); ok {
+		if v {
+			return NewDecimal(1, 0), true, nil
+		}
+		return Decimal{}, true, nil
+	}
+	return Decimal{}, false, nil
+}
+
+func (ar decimalArith) unary(operator OperatorItem, op string, a Decimal) (Decimal, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		return a.Neg(), true, nil
+	case *Plus:
+		return a, true, nil
+	case *Not:
+		if a.Sign() == 0 {
+			return NewDecimal(1, 0), true, nil
+		}
+		return Decimal{}, true, nil
+	}
+	return Decimal{}, false, nil
+}
+
+func (ar decimalArith) call(def DefFunc, name string, args []Decimal) (Decimal, bool, error) {
+	if fn := decimalFunc(def); fn != nil {
+		v, err := fn(args, ar.policy)
+		return v, true, err
+	}
+	if fn := ratFunc(def); fn != nil {
+		rats := make([]*big.Rat, len(args))
+		for i, a := range args {
+			rats[i] = a.Rat()
+		}
+		v, err := fn(rats)
+		if err != nil {
+			return Decimal{}, true, err
+		}
+		return ar.fromRat(v), true, nil
+	}
+	return Decimal{}, false, nil
+}
+
+// decimalFunc 获取函数的定点小数实现
+func decimalFunc(def DefFunc) func(args []Decimal, policy DecimalPolicy) (Decimal, error) {
+	switch t := def.(type) {
+	case DecimalFunc:
+		return t.CallDecimal
+	case *stdFunc:
+		return t.decimal
+	case *stdDiffFunc:
+		return t.decimal
+	}
+	return nil
+}
+
+// decimalRound round函数与float64计算一致，四舍五入远离零，位数为负时舍入到整数位
+func decimalRound(args []Decimal, policy DecimalPolicy) (Decimal, error) {
+	digits := int64(0)
+	if len(args) == 2 {
+		n := args[1].Round(0, big.ToZero).int()
+		if !n.IsInt64() || n.Int64() > maxExactExp || n.Int64() < -maxExactExp {
+			return Decimal{}, errors.New(fmt.Sprintf("digits %s out of range", args[1]))
+		}
+		digits = n.Int64()
+	}
+	if digits >= 0 {
+		return args[0].Round(int(digits), RoundHalfUp), nil
+	}
+	unit := NewDecimal(1, int(digits))
+	q, err := args[0].Quo(unit, 0, RoundHalfUp)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return q.Mul(unit), nil
+}
@@ -0,0 +1,86 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+func evalDecimal(t *testing.T, src string, policy DecimalPolicy) string {
+	t.Helper()
+	ctx := WithOptions(context.Background(), Options{Precision: PrecisionDecimal, Decimal: policy})
+	d, err := EvaluateDecimal(ctx, mustParse(t, src))
+	if err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	return d.String()
+}
+
+func TestDecimalEvaluate(t *testing.T) {
+	tests := []struct {
+		src    string
+		policy DecimalPolicy
+		want   string
+	}{
+		{"0.1+0.2", DecimalPolicy{}, "0.3"},
+		{"1/3", NewDecimalPolicy(4, RoundHalfUp), "0.3333"},
+		{"2/3", NewDecimalPolicy(2, RoundDown), "0.66"},
+		{"5.5%2", DecimalPolicy{}, "1.5"},
+		{"2.665*1", NewDecimalPolicy(2, RoundHalfUp), "2.665"},
+		{"round(2.665, 2)", DecimalPolicy{}, "2.67"},
+		{"1/3", DecimalPolicy{}, "0.333333333333333333"},
+	}
+	for _, tt := range tests {
+		if got := evalDecimal(t, tt.src, tt.policy); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalZeroScale(t *testing.T) {
+	policy := NewDecimalPolicy(0, RoundHalfUp)
+	for src, want := range map[string]string{
+		"5/2":  "3",
+		"2^-2": "0",
+		"7/2":  "4",
+		"-5/2": "-3",
+	} {
+		if got := evalDecimal(t, src, policy); got != want {
+			t.Errorf("%s = %s, want %s", src, got, want)
+		}
+	}
+	if got := evalDecimal(t, "5/2", NewDecimalPolicy(0, RoundHalfEven)); got != "2" {
+		t.Errorf("5/2 half even = %s, want 2", got)
+	}
+}
+
+func TestDecimalRoundMatchesFloat(t *testing.T) {
+	for _, src := range []string{"round(2.5)", "round(-2.5)", "round(0.5)", "round(1234.5, -2)", "round(1250, -2)", "round(-1250, -2)", "round(1.25, 1)"} {
+		expr := mustParse(t, src)
+		f, err := Evaluate(context.Background(), expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := EvaluateDecimal(context.Background(), expr)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if d.Float64() != f {
+			t.Errorf("%s: decimal %s, float %v", src, d, f)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	for src, want := range map[string]string{"1.50": "1.50", "-0.05": "-0.05", "1e3": "1000", "1.5e-2": "0.015"} {
+		d, err := ParseDecimal(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.String() != want {
+			t.Errorf("ParseDecimal(%s) = %s, want %s", src, d, want)
+		}
+	}
+	if _, err := ParseDecimal("1.2.3"); err == nil {
+		t.Error("ParseDecimal(1.2.3) want error")
+	}
+}
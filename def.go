@@ -6,22 +6,32 @@ import (
 )
 
 // 定义全局常量
-var defConst = map[string]float64{
-	"pi":    math.Pi,
-	"e":     math.E,
-	"infty": 0,
-}
+var defConst = builtinConst()
 
 // 定义全局LaTex常量
-var defConstLaTex = map[string]string{
-	"pi":    "π",
-	"e":     "e",
-	"infty": "\\infty",
-}
+var defConstLaTex = builtinConstLaTex()
 
 // FuncExprNode处理对象
 var defFunc map[string]DefFunc = map[string]DefFunc{}
 
+// 内置常量
+func builtinConst() map[string]float64 {
+	return map[string]float64{
+		"pi":    math.Pi,
+		"e":     math.E,
+		"infty": 0,
+	}
+}
+
+// 内置LaTex常量
+func builtinConstLaTex() map[string]string {
+	return map[string]string{
+		"pi":    "π",
+		"e":     "e",
+		"infty": "\\infty",
+	}
+}
+
 // DefFunc 节点运算
 type DefFunc interface {
 	Calculate(ctx context.Context, args ...ExprNode) float64
@@ -34,7 +44,23 @@ type LaTexFunc interface {
 	LaTex(ctx context.Context, args ...ExprNode) string
 }
 
-// DiffExprNodeFunc 微分运算
+// DiffExprNodeFunc 微分运算，返回函数关于首个参数的导数，链式法则由Diff完成
 type DiffExprNodeFunc interface {
 	DiffExprNode(ctx context.Context, args ...ExprNode) ExprNode
 }
+
+// PartialDiffExprNodeFunc 多参数函数的偏导数，返回函数关于第i个参数的偏导数，不可求导时返回nil
+// Diff优先使用，对每个与求导变量相关的参数应用链式法则后求和
+type PartialDiffExprNodeFunc interface {
+	PartialDiffExprNode(ctx context.Context, i int, args ...ExprNode) ExprNode
+}
+
+// EvaluateFunc 以error代替panic返回运算异常，Evaluate优先使用
+type EvaluateFunc interface {
+	Evaluate(ctx context.Context, args ...ExprNode) (float64, error)
+}
+
+// FloatFunc 直接以数值参数计算，Compile生成的Program优先使用，避免构造ExprNode
+type FloatFunc interface {
+	CallFloat(args []float64) (float64, error)
+}
@@ -0,0 +1,301 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Diff 对表达式关于变量v求导
+// 数值、常量及未绑定表达式的变量视为常数，值为字符串或ExprNode的变量按其表达式求导，变量值从Options.Resolver或Parameter获取，
+// 函数节点委托给PartialDiffExprNodeFunc或DiffExprNodeFunc并应用链式法则，Options.AngleUnit为Degree时三角函数导数乘以pi/180，反三角函数乘以180/pi
+func Diff(ctx context.Context, expr ExprNode, v string) (ExprNode, error) {
+	d := &differ{ctx: ctx, env: EnvironmentFrom(ctx), v: v}
+	return d.diff(expr)
+}
+
+// DiffAll 对Parameter.Diff中的每个变量分别求导
+func DiffAll(ctx context.Context, expr ExprNode) (map[string]ExprNode, error) {
+	parameter, err := GetCtxParameter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]ExprNode, len(parameter.Diff))
+	for _, v := range parameter.Diff {
+		d, err := Diff(ctx, expr, v)
+		if err != nil {
+			return nil, err
+		}
+		r[v] = d
+	}
+	return r, nil
+}
+
+// Diff 在当前环境中对表达式关于变量v求导
+func (e *Environment) Diff(ctx context.Context, expr ExprNode, v string) (ExprNode, error) {
+	return Diff(WithEnvironment(ctx, e), expr, v)
+}
+
+type differ struct {
+	ctx       context.Context
+	env       *Environment
+	v         string
+	expanding []string // 正在展开的变量
+}
+
+func (d *differ) diff(expr ExprNode) (ExprNode, error) {
+	switch node := expr.(type) {
+
+	case NumberExprNode, ConstExprNode:
+		return newNumber(0), nil
+
+	case VariableExprNode:
+		if node.Val == d.v {
+			return newNumber(1), nil
+		}
+		return d.diffVariable(node.Val)
+
+	case OperatorExprNode:
+		l, err := d.diff(node.Lhs)
+		if err != nil {
+			return nil, err
+		}
+		r, err := d.diff(node.Rhs)
+		if err != nil {
+			return nil, err
+		}
+		return d.diffOperator(node, l, r)
+
+	case UnaryExprNode:
+		u, err := d.diff(node.Operand)
+		if err != nil {
+			return nil, err
+		}
+		switch node.Op {
+		case "-":
+			return diffSub(newNumber(0), u), nil
+		case "+":
+			return u, nil
+		case "!":
+			return newNumber(0), nil
+		}
+		if isZeroNode(u) {
+			return newNumber(0), nil
+		}
+		return nil, &NoDerivativeError{Name: node.Op}
+
+	case ConditionalExprNode:
+		t, err := d.diff(node.Then)
+		if err != nil {
+			return nil, err
+		}
+		e, err := d.diff(node.Else)
+		if err != nil {
+			return nil, err
+		}
+		if isZeroNode(t) && isZeroNode(e) {
+			return newNumber(0), nil
+		}
+		return ConditionalExprNode{Cond: node.Cond, Then: t, Else: e}, nil
+
+	case FunCallerExprNode:
+		return d.diffFunc(node)
+	}
+
+	return nil, errors.New(fmt.Sprintf("unknown expr node %T", expr))
+}
+
+// diffVariable 按变量绑定的表达式求导，变量值从Options.Resolver或Parameter获取，未绑定的变量视为常数
+func (d *differ) diffVariable(name string) (ExprNode, error) {
+	value, err := resolveVariable(d.ctx, OptionsFrom(d.ctx), name)
+	if err != nil {
+		var unbound *UnboundVariableError
+		if errors.As(err, &unbound) || errors.Is(err, ErrNoParameter) {
+			return newNumber(0), nil
+		}
+		return nil, err
+	}
+	var expr ExprNode
+	switch t := value.(type) {
+	case string:
+		parameter, _ := GetCtxParameter(d.ctx)
+		expr, err = parameter.parseVar(d.env, name, t)
+		if err != nil {
+			return nil, &VariableError{Name: name, Value: t, Err: err}
+		}
+	case ExprNode:
+		expr = t
+	default:
+		return newNumber(0), nil
+	}
+	for i, v := range d.expanding {
+		if v == name {
+			return nil, &CycleError{Chain: append(append([]string{}, d.expanding[i:]...), name)}
+		}
+	}
+	d.expanding = append(d.expanding, name)
+	defer func() {
+		d.expanding = d.expanding[:len(d.expanding)-1]
+	}()
+	return d.diff(expr)
+}
+
+func (d *differ) diffOperator(node OperatorExprNode, l ExprNode, r ExprNode) (ExprNode, error) {
+	switch node.Op {
+	case "+":
+		return diffAdd(l, r), nil
+	case "-":
+		return diffSub(l, r), nil
+	case "*":
+		return diffAdd(diffMul(l, node.Rhs), diffMul(node.Lhs, r)), nil
+	case "/":
+		if isZeroNode(r) {
+			return diffDiv(l, node.Rhs), nil
+		}
+		return diffDiv(
+			diffSub(diffMul(l, node.Rhs), diffMul(node.Lhs, r)),
+			newBinary("^", node.Rhs, newNumber(2))), nil
+	case "^":
+		switch {
+		case isZeroNode(l) && isZeroNode(r):
+			return newNumber(0), nil
+		case isZeroNode(r):
+			// (u^c)' = c * u^(c-1) * u'
+			return diffMul(diffMul(node.Rhs, newBinary("^", node.Lhs, diffSub(node.Rhs, newNumber(1)))), l), nil
+		case isZeroNode(l):
+			// (c^v)' = c^v * ln(c) * v'
+			return diffMul(diffMul(node, newCall("ln", node.Lhs)), r), nil
+		}
+		// (u^v)' = u^v * (v' * ln(u) + v * u' / u)
+		return diffMul(node, diffAdd(
+			diffMul(r, newCall("ln", node.Lhs)),
+			diffDiv(diffMul(node.Rhs, l), node.Lhs))), nil
+	case "%":
+		// 取模按整数截断计算，几乎处处导数为0
+		return newNumber(0), nil
+	case "<", "<=", ">", ">=", "==", "!=", "&&", "||", "!":
+		// 比较及逻辑运算结果为分段常数
+		return newNumber(0), nil
+	}
+	if isZeroNode(l) && isZeroNode(r) {
+		return newNumber(0), nil
+	}
+	return nil, &NoDerivativeError{Name: node.Op}
+}
+
+// diffFunc 函数求导，f(u, ...)' = f'(u, ...) * u'
+// 实现PartialDiffExprNodeFunc的函数对每个与求导变量相关的参数求偏导后求和，否则其余参数须与求导变量无关
+func (d *differ) diffFunc(node FunCallerExprNode) (ExprNode, error) {
+	args := make([]ExprNode, len(node.Arg))
+	independent := true
+	for i, arg := range node.Arg {
+		a, err := d.diff(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = a
+		independent = independent && isZeroNode(a)
+	}
+	if independent {
+		return newNumber(0), nil
+	}
+	def, ok := d.env.Func(node.Name)
+	if !ok {
+		return nil, &FuncError{Name: node.Name, Err: ErrUndefinedFunc}
+	}
+	pd, partial := def.(PartialDiffExprNodeFunc)
+	df, ok := def.(DiffExprNodeFunc)
+	if !partial && !ok {
+		return nil, &NoDerivativeError{Name: node.Name}
+	}
+	var r ExprNode = newNumber(0)
+	for i, a := range args {
+		if isZeroNode(a) {
+			continue
+		}
+		var p ExprNode
+		switch {
+		case partial:
+			p = pd.PartialDiffExprNode(d.ctx, i, node.Arg...)
+		case i == 0:
+			p = df.DiffExprNode(d.ctx, node.Arg...)
+		}
+		if p == nil {
+			return nil, &NoDerivativeError{Name: node.Name}
+		}
+		r = diffAdd(r, diffMul(d.angle(def, p), a))
+	}
+	return r, nil
+}
+
+// angle 非弧度单位时标准库三角函数的导数乘以角度换算系数
+func (d *differ) angle(def DefFunc, df ExprNode) ExprNode {
+	if OptionsFrom(d.ctx).AngleUnit != Degree {
+		return df
+	}
+	var pi ExprNode = newNumber(math.Pi)
+	if v, ok := d.env.Const("pi"); ok && v == math.Pi {
+		pi = ConstExprNode{Name: "pi", Val: v, Str: "3"}
+	}
+	switch funcAngle(def) {
+	case angleArg:
+		return diffDiv(diffMul(df, pi), newNumber(180))
+	case angleResult:
+		return diffDiv(diffMul(df, newNumber(180)), pi)
+	}
+	return df
+}
+
+func isZeroNode(expr ExprNode) bool {
+	n, ok := expr.(NumberExprNode)
+	return ok && n.Val == 0
+}
+
+func isOneNode(expr ExprNode) bool {
+	n, ok := expr.(NumberExprNode)
+	return ok && n.Val == 1
+}
+
+func diffAdd(a ExprNode, b ExprNode) ExprNode {
+	if isZeroNode(a) {
+		return b
+	}
+	if isZeroNode(b) {
+		return a
+	}
+	return newBinary("+", a, b)
+}
+
+func diffSub(a ExprNode, b ExprNode) ExprNode {
+	if isZeroNode(b) {
+		return a
+	}
+	if isZeroNode(a) {
+		return newNeg(b)
+	}
+	return newBinary("-", a, b)
+}
+
+func diffMul(a ExprNode, b ExprNode) ExprNode {
+	if isZeroNode(a) || isZeroNode(b) {
+		return newNumber(0)
+	}
+	if isOneNode(a) {
+		return b
+	}
+	if isOneNode(b) {
+		return a
+	}
+	return newBinary("*", a, b)
+}
+
+func diffDiv(a ExprNode, b ExprNode) ExprNode {
+	if isZeroNode(a) {
+		return newNumber(0)
+	}
+	if isOneNode(b) {
+		return a
+	}
+	return newBinary("/", a, b)
+}
@@ -0,0 +1,121 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []string{"x^3 + 2*x", "sin(x)*cos(x)", "x/(1+x^2)", "exp(2*x)", "ln(x)", "2^x", "x^x", "sqrt(x)", "atan(x)", "x > 1 ? x^2 : 3*x"}
+	for _, src := range tests {
+		checkDerivative(t, context.Background(), src, 0.7)
+	}
+}
+
+func TestDiffMultiArg(t *testing.T) {
+	for _, src := range []string{"pow(2, x)", "pow(x, x)", "log(8, x)", "log(x, x + 1)", "hypot(3, x)", "hypot(x, 2*x)"} {
+		checkDerivative(t, context.Background(), src, 0.7)
+	}
+}
+
+func TestDiffResolver(t *testing.T) {
+	r := MapResolver{"y": "x^2", "z": mustParse(t, "3*y")}
+	ctx := WithOptions(context.Background(), Options{Resolver: r})
+	d, err := Diff(ctx, mustParse(t, "z + x"), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Evaluate(WithOptions(context.Background(), Options{Resolver: MapResolver{"x": 2}}), d)
+	if err != nil || got != 13 {
+		t.Errorf("d/dx z + x = %s = %v, want 13 (%v)", Format(d), got, err)
+	}
+	failing := ResolverFunc(func(ctx context.Context, name string) (any, error) {
+		return nil, errors.New("resolver failed")
+	})
+	if _, err := Diff(WithOptions(context.Background(), Options{Resolver: failing}), mustParse(t, "y"), "x"); err == nil {
+		t.Error("want resolver error")
+	}
+}
+
+func TestDiffDegree(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{AngleUnit: Degree})
+	for _, src := range []string{"sin(x)", "cos(2*x)", "tan(x)", "asin(x/2)", "acos(x/2)", "atan(x)"} {
+		checkDerivative(t, ctx, src, 0.7)
+	}
+	d, err := Diff(ctx, mustParse(t, "sin(x)"), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Evaluate(WithParameter(ctx, NewParameter(map[string]any{"x": 0}, nil)), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-math.Pi/180) > 1e-15 {
+		t.Errorf("d/dx sin(x) at 0 in degree = %v (%s)", got, Format(d))
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	_, err := Diff(context.Background(), mustParse(t, "floor(x)"), "x")
+	var noDiff *NoDerivativeError
+	if !errors.As(err, &noDiff) || noDiff.Name != "floor" {
+		t.Errorf("floor: %v", err)
+	}
+	d, err := Diff(context.Background(), mustParse(t, "floor(y) + x"), "x")
+	if err != nil || Format(d) != "1" {
+		t.Errorf("independent argument: %v %v", d, err)
+	}
+}
+
+// checkDerivative 以中心差分校验导数
+func checkDerivative(t *testing.T, ctx context.Context, src string, x float64) {
+	t.Helper()
+	expr := mustParse(t, src)
+	d, err := Diff(ctx, expr, "x")
+	if err != nil {
+		t.Errorf("%s: %v", src, err)
+		return
+	}
+	at := func(e ExprNode, v float64) float64 {
+		r, err := Evaluate(WithParameter(ctx, NewParameter(map[string]any{"x": v}, nil)), e)
+		if err != nil {
+			t.Fatalf("%s: %v", Format(e), err)
+		}
+		return r
+	}
+	const h = 1e-6
+	want := (at(expr, x+h) - at(expr, x-h)) / (2 * h)
+	if got := at(d, x); math.Abs(got-want) > 1e-6*math.Max(1, math.Abs(want)) {
+		t.Errorf("d/dx %s = %s = %v at %v, want %v", src, Format(d), got, x, want)
+	}
+}
+
+func TestDiffAll(t *testing.T) {
+	ctx := WithParameter(context.Background(), NewParameter(map[string]any{"z": "x * y"}, []string{"x", "y"}))
+	got, err := DiffAll(ctx, mustParse(t, "x^2 + z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("DiffAll = %v", got)
+	}
+	vars := WithParameter(context.Background(), NewParameter(map[string]any{"x": 3, "y": 5}, nil))
+	for v, want := range map[string]float64{"x": 11, "y": 3} {
+		d, err := Evaluate(vars, got[v])
+		if err != nil || d != want {
+			t.Errorf("d/d%s = %s = %v, want %v (%v)", v, Format(got[v]), d, want, err)
+		}
+	}
+}
+
+func TestDiffCycle(t *testing.T) {
+	ctx := WithParameter(context.Background(), NewParameter(map[string]any{"a": "b + x", "b": "2 * a"}, nil))
+	_, err := Diff(ctx, mustParse(t, "a"), "x")
+	var cycle *CycleError
+	if !errors.As(err, &cycle) || strings.Join(cycle.Chain, " -> ") != "a -> b -> a" {
+		t.Errorf("cycle: %v", err)
+	}
+}
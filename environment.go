@@ -0,0 +1,400 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Environment 运行环境，持有函数、常量、LaTex常量及操作符定义
+// 不同环境之间相互隔离，注册与解析可并发进行
+type Environment struct {
+	mu   sync.RWMutex
+	base *Environment
+
+	funcs      map[string]DefFunc
+	consts     map[string]float64
+	constLaTex map[string]string
+	operators  map[byte]OperatorItem
+	symbols    map[string]OperatorItem // 多字符操作符
+	legacy     bool                    // 兼容旧版优先级
+	rev        uint64                  // 定义变更次数，用于判断解析缓存是否失效
+}
+
+// 默认运行环境，包级别的注册、解析及计算函数均作用于该环境
+var defaultEnv = &Environment{
+	funcs:      defFunc,
+	consts:     defConst,
+	constLaTex: defConstLaTex,
+	operators:  Operators,
+	symbols:    SymbolOperators,
+}
+
+type envCtxKey struct{}
+
+func init() {
+	if err := defaultEnv.RegStdFuncs(); err != nil {
+		panic(err)
+	}
+}
+
+// DefaultEnvironment 获取默认运行环境
+func DefaultEnvironment() *Environment {
+	return defaultEnv
+}
+
+// NewEnvironment 创建运行环境
+// base不为空时继承base中的定义（base后续的注册同样可见），自身注册的同名定义优先；
+// base为空时以内置常量、操作符及标准库函数初始化，可通过UnregStdFuncs移除
+func NewEnvironment(base *Environment) *Environment {
+	e := &Environment{
+		base:       base,
+		funcs:      map[string]DefFunc{},
+		consts:     map[string]float64{},
+		constLaTex: map[string]string{},
+		operators:  map[byte]OperatorItem{},
+		symbols:    map[string]OperatorItem{},
+	}
+	if base != nil {
+		e.legacy = base.LegacyPrecedence()
+	} else {
+		e.consts = builtinConst()
+		e.constLaTex = builtinConstLaTex()
+		e.operators = builtinOperators()
+		e.symbols = builtinSymbolOperators()
+		if err := e.RegStdFuncs(); err != nil {
+			panic(err)
+		}
+	}
+	return e
+}
+
+// Clone 复制运行环境，展开继承关系，复制后与原环境互不影响
+func (e *Environment) Clone() *Environment {
+	c := &Environment{
+		funcs:      map[string]DefFunc{},
+		consts:     map[string]float64{},
+		constLaTex: map[string]string{},
+		operators:  map[byte]OperatorItem{},
+		symbols:    map[string]OperatorItem{},
+	}
+	e.copyTo(c)
+	c.legacy = e.LegacyPrecedence()
+	return c
+}
+
+// SetLegacyPrecedence 设置兼容旧版优先级：^左结合，一元负号以 0 - x 形式解析且优先级高于^
+func (e *Environment) SetLegacyPrecedence(legacy bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.legacy = legacy
+	e.rev++
+}
+
+// LegacyPrecedence 是否兼容旧版优先级
+func (e *Environment) LegacyPrecedence() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.legacy
+}
+
+func (e *Environment) copyTo(c *Environment) {
+	if e.base != nil {
+		e.base.copyTo(c)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for k, v := range e.funcs {
+		if v == nil {
+			delete(c.funcs, k)
+			continue
+		}
+		c.funcs[k] = v
+	}
+	for k, v := range e.consts {
+		c.consts[k] = v
+	}
+	for k, v := range e.constLaTex {
+		c.constLaTex[k] = v
+	}
+	for k, v := range e.operators {
+		c.operators[k] = v
+	}
+	for k, v := range e.symbols {
+		c.symbols[k] = v
+	}
+}
+
+// RegDefFunc 注册函数，仅校验当前环境，允许覆盖base中的同名函数及标准库函数，
+// 注册标准库之前已存在的同名自定义函数（如sin、log、max）可继续注册
+func (e *Environment) RegDefFunc(name string, df DefFunc) error {
+	if len(name) == 0 {
+		return errors.New("RegFunction name is not empty")
+	}
+	if df.Argc() < -1 {
+		return errors.New("RegFunction argc should be -1, 0, or a positive integer")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if f, ok := e.funcs[name]; ok && f != nil && !isStdFunc(f) {
+		return errors.New("RegFunction name is already exist")
+	}
+	e.funcs[name] = df
+	e.rev++
+	return nil
+}
+
+// UnregDefFunc 移除函数，base中的同名函数在当前环境同样不可见
+func (e *Environment) UnregDefFunc(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rev++
+	if e.base == nil {
+		delete(e.funcs, name)
+		return
+	}
+	e.funcs[name] = nil
+}
+
+// RegConst 注册常量
+func (e *Environment) RegConst(name string, value float64) error {
+	if len(name) == 0 {
+		return errors.New("RegConst name is not empty")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.consts[name]; ok {
+		return errors.New("RegConst name is already exist")
+	}
+	e.consts[name] = value
+	e.rev++
+	return nil
+}
+
+// RegConstLaTex 注册常量latex
+func (e *Environment) RegConstLaTex(name string, value string) error {
+	if len(name) == 0 {
+		return errors.New("RegConstLaTex name is not empty")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.constLaTex[name]; ok {
+		return errors.New("RegConstLaTex name is already exist")
+	}
+	e.constLaTex[name] = value
+	return nil
+}
+
+// RegOperator 注册操作符，同名操作符将被替换，实现SymbolOperator的操作符按Symbol注册
+func (e *Environment) RegOperator(item OperatorItem) error {
+	if item == nil {
+		return errors.New("RegOperator item is not empty")
+	}
+	symbol := operatorSymbol(item)
+	if len(symbol) > maxSymbolLen {
+		return errors.New(fmt.Sprintf("RegOperator symbol `%s` is too long", symbol))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rev++
+	if len(symbol) > 1 {
+		e.symbols[symbol] = item
+		return nil
+	}
+	e.operators[item.Name()] = item
+	return nil
+}
+
+// revision 当前环境及base的定义变更次数
+func (e *Environment) revision() uint64 {
+	e.mu.RLock()
+	r, base := e.rev, e.base
+	e.mu.RUnlock()
+	if base != nil {
+		r += base.revision()
+	}
+	return r
+}
+
+// Func 获取函数
+func (e *Environment) Func(name string) (DefFunc, bool) {
+	e.mu.RLock()
+	f, ok := e.funcs[name]
+	e.mu.RUnlock()
+	if ok {
+		return f, f != nil
+	}
+	if e.base != nil {
+		return e.base.Func(name)
+	}
+	return nil, false
+}
+
+// Const 获取常量
+func (e *Environment) Const(name string) (float64, bool) {
+	e.mu.RLock()
+	v, ok := e.consts[name]
+	e.mu.RUnlock()
+	if ok {
+		return v, true
+	}
+	if e.base != nil {
+		return e.base.Const(name)
+	}
+	return 0, false
+}
+
+// ConstLaTex 获取常量latex
+func (e *Environment) ConstLaTex(name string) (string, bool) {
+	e.mu.RLock()
+	v, ok := e.constLaTex[name]
+	e.mu.RUnlock()
+	if ok {
+		return v, true
+	}
+	if e.base != nil {
+		return e.base.ConstLaTex(name)
+	}
+	return "", false
+}
+
+// Operator 获取操作符
+func (e *Environment) Operator(symbol string) (OperatorItem, bool) {
+	if len(symbol) == 0 {
+		return nil, false
+	}
+	e.mu.RLock()
+	var o OperatorItem
+	var ok bool
+	if len(symbol) == 1 {
+		o, ok = e.operators[symbol[0]]
+	} else {
+		o, ok = e.symbols[symbol]
+	}
+	e.mu.RUnlock()
+	if ok {
+		return o, true
+	}
+	if e.base != nil {
+		return e.base.Operator(symbol)
+	}
+	return nil, false
+}
+
+// matchOperator 按最长匹配获取s开头的操作符
+func (e *Environment) matchOperator(s string) (OperatorItem, string, bool) {
+	n := maxSymbolLen
+	if len(s) < n {
+		n = len(s)
+	}
+	for ; n > 0; n-- {
+		if o, ok := e.Operator(s[:n]); ok {
+			return o, s[:n], true
+		}
+	}
+	return nil, "", false
+}
+
+// Parse 按当前环境的操作符拆分token
+func (e *Environment) Parse(s string) ([]*Token, error) {
+	if len(s) == 0 {
+		return nil, ErrEmptyExpression
+	}
+	p := &Parser{
+		Source: s,
+		env:    e,
+		err:    nil,
+		ch:     s[0],
+	}
+	toks := p.parse()
+	if p.err != nil {
+		return nil, p.err
+	}
+	return toks, nil
+}
+
+// NewAST 创建基于当前环境的抽象语法树
+func (e *Environment) NewAST(toks []*Token, s string) *AST {
+	a := NewAST(toks, s)
+	a.env = e
+	a.legacy = e.LegacyPrecedence()
+	return a
+}
+
+// ParseExpression 解析表达式
+func (e *Environment) ParseExpression(s string) (ExprNode, error) {
+	toks, err := e.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	ast := e.NewAST(toks, s)
+	if ast.Err != nil {
+		return nil, ast.Err
+	}
+	ar := ast.ParseExpression()
+	if ast.Err != nil {
+		return nil, ast.Err
+	}
+	return ar, nil
+}
+
+// Calculate 在当前环境中计算节点，异常时panic
+func (e *Environment) Calculate(expr ExprNode, ctx context.Context) float64 {
+	return Calculate(expr, WithEnvironment(ctx, e))
+}
+
+// Evaluate 在当前环境中计算节点，异常以error返回
+func (e *Environment) Evaluate(ctx context.Context, expr ExprNode) (float64, error) {
+	return Evaluate(WithEnvironment(ctx, e), expr)
+}
+
+// CalculateValue 在当前环境中计算带类型的结果，异常时panic
+func (e *Environment) CalculateValue(expr ExprNode, ctx context.Context) Value {
+	return CalculateValue(expr, WithEnvironment(ctx, e))
+}
+
+// EvaluateValue 在当前环境中计算带类型的结果，异常以error返回
+func (e *Environment) EvaluateValue(ctx context.Context, expr ExprNode) (Value, error) {
+	return EvaluateValue(WithEnvironment(ctx, e), expr)
+}
+
+// CalculateComplex 在当前环境中以复数计算，异常时panic
+func (e *Environment) CalculateComplex(expr ExprNode, ctx context.Context) complex128 {
+	return CalculateComplex(expr, WithEnvironment(ctx, e))
+}
+
+// EvaluateComplex 在当前环境中以复数计算，异常以error返回
+func (e *Environment) EvaluateComplex(ctx context.Context, expr ExprNode) (complex128, error) {
+	return EvaluateComplex(WithEnvironment(ctx, e), expr)
+}
+
+// CalculateInterval 在当前环境中以区间计算，异常时panic
+func (e *Environment) CalculateInterval(expr ExprNode, ctx context.Context) Interval {
+	return CalculateInterval(expr, WithEnvironment(ctx, e))
+}
+
+// EvaluateInterval 在当前环境中以区间计算，异常以error返回
+func (e *Environment) EvaluateInterval(ctx context.Context, expr ExprNode) (Interval, error) {
+	return EvaluateInterval(WithEnvironment(ctx, e), expr)
+}
+
+// ToExprStr 在当前环境中打印节点
+func (e *Environment) ToExprStr(expr ExprNode, ctx context.Context) string {
+	return ToExprStr(expr, WithEnvironment(ctx, e))
+}
+
+// WithEnvironment 将运行环境写入上下文，计算过程中的函数、操作符及变量解析均使用该环境
+func WithEnvironment(ctx context.Context, e *Environment) context.Context {
+	return context.WithValue(ctx, envCtxKey{}, e)
+}
+
+// EnvironmentFrom 获取上下文中的运行环境，未设置时返回默认运行环境
+func EnvironmentFrom(ctx context.Context) *Environment {
+	if ctx != nil {
+		if e, ok := ctx.Value(envCtxKey{}).(*Environment); ok && e != nil {
+			return e
+		}
+	}
+	return defaultEnv
+}
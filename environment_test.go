@@ -0,0 +1,102 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEnvironmentIsolation(t *testing.T) {
+	a := NewEnvironment(nil)
+	b := NewEnvironment(nil)
+	if err := a.RegConst("k", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RegDefFunc("count", &countFunc{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Const("k"); ok {
+		t.Error("const registered in a is visible in b")
+	}
+	if _, ok := b.Func("count"); ok {
+		t.Error("function registered in a is visible in b")
+	}
+	if _, ok := DefaultEnvironment().Const("k"); ok {
+		t.Error("const registered in a is visible in the default environment")
+	}
+	if err := a.RegDefFunc("count", &countFunc{}); err == nil {
+		t.Error("want error for duplicate function")
+	}
+}
+
+func TestEnvironmentInheritance(t *testing.T) {
+	base := NewEnvironment(nil)
+	child := NewEnvironment(base)
+	if err := base.RegConst("k", 2); err != nil {
+		t.Fatal(err)
+	}
+	// base后续的注册在child中可见
+	expr, err := child.ParseExpression("k * 3 + sin(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := child.Evaluate(context.Background(), expr); err != nil || v != 6 {
+		t.Errorf("child evaluate = %v, %v", v, err)
+	}
+
+	child.UnregDefFunc("sin")
+	if _, ok := child.Func("sin"); ok {
+		t.Error("sin is visible in child after UnregDefFunc")
+	}
+	if _, ok := base.Func("sin"); !ok {
+		t.Error("UnregDefFunc in child removed sin from base")
+	}
+
+	clone := child.Clone()
+	if err := base.RegConst("k2", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := clone.Const("k2"); ok {
+		t.Error("clone sees registrations made after Clone")
+	}
+	if _, ok := clone.Func("sin"); ok {
+		t.Error("clone sees a function removed from child")
+	}
+	if v, ok := clone.Const("k"); !ok || v != 2 {
+		t.Errorf("clone const k = %v, %v", v, ok)
+	}
+}
+
+func TestEnvironmentFromContext(t *testing.T) {
+	env := NewEnvironment(nil)
+	if err := env.RegConst("k", 5); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.ParseExpression("k + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if EnvironmentFrom(context.Background()) != DefaultEnvironment() {
+		t.Error("EnvironmentFrom without environment should return the default environment")
+	}
+	if v, err := Evaluate(WithEnvironment(context.Background(), env), expr); err != nil || v != 6 {
+		t.Errorf("evaluate = %v, %v", v, err)
+	}
+}
+
+// 自定义函数可覆盖同名标准库函数，覆盖后不可重复注册
+func TestEnvironmentOverrideStdFunc(t *testing.T) {
+	env := NewEnvironment(nil)
+	f := &countFunc{}
+	if err := env.RegDefFunc("max", f); err != nil {
+		t.Fatalf("override max: %v", err)
+	}
+	if got, ok := env.Func("max"); !ok || got != DefFunc(f) {
+		t.Errorf("max = %v, want the registered function", got)
+	}
+	if err := env.RegDefFunc("max", &countFunc{}); err == nil {
+		t.Error("want error for duplicate user function")
+	}
+	if got, _ := DefaultEnvironment().Func("max"); got == DefFunc(f) {
+		t.Error("override is visible in the default environment")
+	}
+}
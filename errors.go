@@ -0,0 +1,172 @@
+package mathastc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNoParameter 上下文中未找到Parameter对象
+	ErrNoParameter = errors.New("no parameter found")
+	// ErrDivisionByZero 除数为零
+	ErrDivisionByZero = errors.New("a division by zero")
+	// ErrUndefinedFunc 函数未定义
+	ErrUndefinedFunc = errors.New("function is undefined")
+	// ErrUndefinedOperator 操作符未定义
+	ErrUndefinedOperator = errors.New("operator is undefined")
+	// ErrUnknownValueType 变量值类型不支持
+	ErrUnknownValueType = errors.New("unknown value type")
+	// ErrLimitExceeded 超出计算限制
+	ErrLimitExceeded = errors.New("evaluation limit exceeded")
+	// ErrNotFinite 严格模式下运算结果为NaN或Inf
+	ErrNotFinite = errors.New("result is not a finite number")
+	// ErrTypeMismatch 操作数类型不支持
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrInexact 高精度模式下运算没有对应精度的实现
+	ErrInexact = errors.New("operation cannot be computed at the requested precision")
+	// ErrEmptyExpression 表达式为空
+	ErrEmptyExpression = errors.New("empty expression")
+)
+
+// TypeError 操作符或函数的操作数类型不支持
+type TypeError struct {
+	Name  string      // 操作符或函数，为空时表示数值计算
+	Want  string      // 支持的类型
+	Kinds []ValueKind // 操作数类型
+}
+
+func (e *TypeError) Error() string {
+	kinds := make([]string, len(e.Kinds))
+	for i, k := range e.Kinds {
+		kinds[i] = k.String()
+	}
+	if e.Name == "" {
+		return fmt.Sprintf("%v: want %s but get %s", ErrTypeMismatch, e.Want, strings.Join(kinds, ", "))
+	}
+	return fmt.Sprintf("%v: `%s` want %s but get %s", ErrTypeMismatch, e.Name, e.Want, strings.Join(kinds, ", "))
+}
+
+func (e *TypeError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// UnboundVariableError 变量未绑定值
+type UnboundVariableError struct {
+	Name string
+}
+
+func (e *UnboundVariableError) Error() string {
+	return fmt.Sprintf("no parameter value found for %s", e.Name)
+}
+
+// VariableError 变量值解析或计算异常
+type VariableError struct {
+	Name  string
+	Value any
+	Err   error
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("variable `%s` = %v: %v", e.Name, e.Value, e.Err)
+}
+
+func (e *VariableError) Unwrap() error {
+	return e.Err
+}
+
+// OperatorError 操作符运算异常，记录操作符及左右操作数
+type OperatorError struct {
+	Op  string
+	Lhs float64
+	Rhs float64
+	Err error
+}
+
+func (e *OperatorError) Error() string {
+	return fmt.Sprintf("violation of arithmetic specification: %v in ExprASTResult: [%g%s%g]",
+		e.Err,
+		e.Lhs,
+		e.Op,
+		e.Rhs)
+}
+
+func (e *OperatorError) Unwrap() error {
+	return e.Err
+}
+
+// FuncError 函数调用异常
+type FuncError struct {
+	Name string
+	Err  error
+}
+
+func (e *FuncError) Error() string {
+	return fmt.Sprintf("function `%s`: %v", e.Name, e.Err)
+}
+
+func (e *FuncError) Unwrap() error {
+	return e.Err
+}
+
+// EvalError 节点计算异常，Expr为出错的子表达式
+type EvalError struct {
+	Expr ExprNode
+	Err  error
+}
+
+func (e *EvalError) Error() string {
+	if span := e.Span(); span.IsValid() {
+		return fmt.Sprintf("%v\nin %s at offset %d-%d", e.Err, e.Expr.ToStr(), span.Start, span.End)
+	}
+	return fmt.Sprintf("%v\nin %s", e.Err, e.Expr.ToStr())
+}
+
+// Span 出错的子表达式在源码中的位置
+func (e *EvalError) Span() Span {
+	return SpanOf(e.Expr)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError 变量循环引用，Chain为引用链，如 a -> b -> a
+type CycleError struct {
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("variable reference cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// LimitError 超出计算限制，见Limits
+type LimitError struct {
+	Name  string
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s exceeds %d", ErrLimitExceeded, e.Name, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// recoverError 将recover得到的值转换为error
+func recoverError(e any) error {
+	if err, ok := e.(error); ok {
+		return err
+	}
+	return errors.New(fmt.Sprint(e))
+}
+
+// NoDerivativeError 函数或操作符缺少求导规则
+type NoDerivativeError struct {
+	Name string
+}
+
+func (e *NoDerivativeError) Error() string {
+	return fmt.Sprintf("no derivative rule for `%s`", e.Name)
+}
@@ -1,16 +1,32 @@
 package mathastc
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // ExprNode 抽象语法树
 type ExprNode interface {
 	ToStr() string
 }
 
+// Span 节点在源码中的位置，[Start, End)为字节偏移，非解析生成的节点为零值
+type Span struct {
+	Start int
+	End   int
+}
+
+// IsValid 是否记录了源码位置
+func (s Span) IsValid() bool {
+	return s.End > s.Start
+}
+
 // NumberExprNode 数值节点
 type NumberExprNode struct {
-	Val float64
-	Str string
+	Val  float64
+	Str  string
+	Span Span
 }
 
 func (n NumberExprNode) ToStr() string {
@@ -26,6 +42,7 @@ type OperatorExprNode struct {
 	Lhs  ExprNode
 	Rhs  ExprNode
 	Flag bool
+	Span Span
 }
 
 func (o OperatorExprNode) ToStr() string {
@@ -37,22 +54,44 @@ func (o OperatorExprNode) ToStr() string {
 	)
 }
 
+// UnaryExprNode 一元操作节点
+type UnaryExprNode struct {
+	Op      string
+	Operand ExprNode
+	Span    Span
+}
+
+func (u UnaryExprNode) ToStr() string {
+	return fmt.Sprintf(
+		"UnaryExprNode: (%s %s)",
+		u.Op,
+		u.Operand.ToStr(),
+	)
+}
+
 // FunCallerExprNode 函数表达式节点
 type FunCallerExprNode struct {
 	Name string
 	Arg  []ExprNode
+	Span Span
 }
 
 func (f FunCallerExprNode) ToStr() string {
+	args := make([]string, len(f.Arg))
+	for i, arg := range f.Arg {
+		args[i] = arg.ToStr()
+	}
 	return fmt.Sprintf(
-		"FunCallerExprNode:%s",
+		"FunCallerExprNode:%s(%s)",
 		f.Name,
+		strings.Join(args, ", "),
 	)
 }
 
 // VariableExprNode 数值节点
 type VariableExprNode struct {
-	Val string
+	Val  string
+	Span Span
 }
 
 func (v VariableExprNode) ToStr() string {
@@ -62,11 +101,29 @@ func (v VariableExprNode) ToStr() string {
 	)
 }
 
+// ConditionalExprNode 条件运算节点 Cond ? Then : Else，Cond非0为真
+type ConditionalExprNode struct {
+	Cond ExprNode
+	Then ExprNode
+	Else ExprNode
+	Span Span
+}
+
+func (c ConditionalExprNode) ToStr() string {
+	return fmt.Sprintf(
+		"ConditionalExprNode: (%s ? %s : %s)",
+		c.Cond.ToStr(),
+		c.Then.ToStr(),
+		c.Else.ToStr(),
+	)
+}
+
 // ConstExprNode 常量节点
 type ConstExprNode struct {
 	Name string
 	Str  string
 	Val  float64
+	Span Span
 }
 
 func (c ConstExprNode) ToStr() string {
@@ -76,3 +133,101 @@ func (c ConstExprNode) ToStr() string {
 		c.Str,
 	)
 }
+
+// StringExprNode 字符串节点
+type StringExprNode struct {
+	Val  string
+	Span Span
+}
+
+func (s StringExprNode) ToStr() string {
+	return fmt.Sprintf(
+		"StringExprNode:%s",
+		strconv.Quote(s.Val),
+	)
+}
+
+// SpanOf 获取节点在源码中的位置
+func SpanOf(expr ExprNode) Span {
+	switch node := expr.(type) {
+	case NumberExprNode:
+		return node.Span
+	case OperatorExprNode:
+		return node.Span
+	case UnaryExprNode:
+		return node.Span
+	case FunCallerExprNode:
+		return node.Span
+	case VariableExprNode:
+		return node.Span
+	case ConditionalExprNode:
+		return node.Span
+	case ConstExprNode:
+		return node.Span
+	case StringExprNode:
+		return node.Span
+	}
+	return Span{}
+}
+
+// withSpan 设置节点在源码中的位置
+func withSpan(expr ExprNode, span Span) ExprNode {
+	switch node := expr.(type) {
+	case NumberExprNode:
+		node.Span = span
+		return node
+	case OperatorExprNode:
+		node.Span = span
+		return node
+	case UnaryExprNode:
+		node.Span = span
+		return node
+	case FunCallerExprNode:
+		node.Span = span
+		return node
+	case VariableExprNode:
+		node.Span = span
+		return node
+	case ConditionalExprNode:
+		node.Span = span
+		return node
+	case ConstExprNode:
+		node.Span = span
+		return node
+	case StringExprNode:
+		node.Span = span
+		return node
+	}
+	return expr
+}
+
+// newNumber 创建数值节点
+func newNumber(f float64) NumberExprNode {
+	return NumberExprNode{Val: f, Str: Float64ToStr(f)}
+}
+
+// newBinary 创建二元操作节点
+func newBinary(op string, lhs ExprNode, rhs ExprNode) OperatorExprNode {
+	return OperatorExprNode{Op: op, Lhs: lhs, Rhs: rhs}
+}
+
+// newNeg 创建取反节点
+func newNeg(x ExprNode) UnaryExprNode {
+	return UnaryExprNode{Op: "-", Operand: x}
+}
+
+// negOperand 获取取反节点的操作数，兼容 0 - x 形式
+func negOperand(expr ExprNode) (ExprNode, bool) {
+	switch node := expr.(type) {
+	case UnaryExprNode:
+		return node.Operand, node.Op == "-"
+	case OperatorExprNode:
+		return node.Rhs, isUnaryMinus(node)
+	}
+	return nil, false
+}
+
+// newCall 创建函数调用节点
+func newCall(name string, args ...ExprNode) FunCallerExprNode {
+	return FunCallerExprNode{Name: name, Arg: args}
+}
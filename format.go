@@ -0,0 +1,179 @@
+package mathastc
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// atomPrecedence 数值、变量、函数调用等不需要括号的节点
+const atomPrecedence = math.MaxInt32
+
+// Format 以最少的括号打印表达式，ParseExpression生成的表达式满足ParseExpression(Format(e))与e结构相等
+// 负数字面量打印为一元负号
+func Format(expr ExprNode) string {
+	return defaultEnv.Format(expr)
+}
+
+// Format 按当前环境的操作符权重及结合性打印表达式
+func (e *Environment) Format(expr ExprNode) string {
+	p := &printer{env: e, legacy: e.LegacyPrecedence()}
+	return p.format(expr)
+}
+
+type printer struct {
+	env    *Environment
+	legacy bool
+}
+
+func (p *printer) format(expr ExprNode) string {
+	switch node := expr.(type) {
+
+	case NumberExprNode:
+		if node.Str != "" {
+			return node.Str
+		}
+		return Float64ToStr(node.Val)
+
+	case ConstExprNode:
+		return node.Name
+
+	case VariableExprNode:
+		return node.Val
+
+	case StringExprNode:
+		return strconv.Quote(node.Val)
+
+	case FunCallerExprNode:
+		args := make([]string, len(node.Arg))
+		for i, arg := range node.Arg {
+			args[i] = p.format(arg)
+		}
+		return node.Name + "(" + strings.Join(args, ", ") + ")"
+
+	case UnaryExprNode:
+		operand := p.format(node.Operand)
+		if p.unaryParens(node.Operand) {
+			operand = "(" + operand + ")"
+		}
+		return node.Op + operand
+
+	case ConditionalExprNode:
+		cond := p.format(node.Cond)
+		if _, ok := node.Cond.(ConditionalExprNode); ok {
+			cond = "(" + cond + ")"
+		}
+		return cond + " ? " + p.format(node.Then) + " : " + p.format(node.Else)
+
+	case OperatorExprNode:
+		if isPrefixOperand(node.Lhs) {
+			operand := p.format(node.Rhs)
+			if p.precedence(node.Rhs) != atomPrecedence {
+				operand = "(" + operand + ")"
+			}
+			return node.Op + operand
+		}
+		l := p.format(node.Lhs)
+		if p.needParens(node, node.Lhs, false) {
+			l = "(" + l + ")"
+		}
+		r := p.format(node.Rhs)
+		if p.needParens(node, node.Rhs, true) {
+			r = "(" + r + ")"
+		}
+		if node.Op == "^" {
+			return l + node.Op + r
+		}
+		return l + " " + node.Op + " " + r
+	}
+
+	if expr == nil {
+		return ""
+	}
+	return expr.ToStr()
+}
+
+// precedence 节点作为操作数时的权重，未定义的操作符返回-1
+func (p *printer) precedence(expr ExprNode) int {
+	switch node := expr.(type) {
+	case OperatorExprNode:
+		if isPrefixOperand(node.Lhs) {
+			// 旧版前缀操作符只作用于紧随的操作数
+			if p.legacy {
+				return atomPrecedence
+			}
+			return UnaryPrecedence
+		}
+		if o, ok := p.env.Operator(node.Op); ok {
+			return o.Precedence()
+		}
+		return -1
+	case UnaryExprNode:
+		return UnaryPrecedence
+	case ConditionalExprNode:
+		return -1
+	case NumberExprNode:
+		if isNegativeNumber(node) {
+			return UnaryPrecedence
+		}
+	}
+	return atomPrecedence
+}
+
+// unaryParens 一元操作符的操作数是否需要括号，操作数吸收权重高于一元操作符的二元运算
+func (p *printer) unaryParens(operand ExprNode) bool {
+	prec := p.precedence(operand)
+	if p.legacy {
+		return prec != atomPrecedence
+	}
+	return prec <= UnaryPrecedence && !isUnaryNode(operand)
+}
+
+// needParens 二元操作符的子节点是否需要括号
+func (p *printer) needParens(parent OperatorExprNode, child ExprNode, right bool) bool {
+	o, ok := p.env.Operator(parent.Op)
+	if !ok {
+		return p.precedence(child) != atomPrecedence
+	}
+	prec := o.Precedence()
+	cp := p.precedence(child)
+	if right && isUnaryNode(child) && !p.legacy {
+		// 右操作数的一元操作符向右吸收高权重运算，如 2^-x^2 => 2^(-(x^2))
+		return false
+	}
+	if cp != prec {
+		return cp < prec
+	}
+	if isUnaryNode(child) {
+		return false
+	}
+	assoc := LeftAssoc
+	if !p.legacy {
+		assoc = operatorAssoc(o)
+	}
+	if right {
+		return assoc == LeftAssoc
+	}
+	return assoc == RightAssoc
+}
+
+// isUnaryNode 是否为一元操作节点，包括旧版的 0 - x 形式
+func isUnaryNode(expr ExprNode) bool {
+	switch node := expr.(type) {
+	case UnaryExprNode:
+		return true
+	case OperatorExprNode:
+		return isPrefixOperand(node.Lhs)
+	case NumberExprNode:
+		return isNegativeNumber(node)
+	}
+	return false
+}
+
+// isNegativeNumber 负数字面量按一元负号处理
+func isNegativeNumber(n NumberExprNode) bool {
+	if n.Str != "" {
+		return strings.HasPrefix(n.Str, "-")
+	}
+	return math.Signbit(n.Val) && n.Val != 0
+}
@@ -0,0 +1,54 @@
+package mathastc
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"(1+2)*3", "(1 + 2) * 3"},
+		{"1+(2*3)", "1 + 2 * 3"},
+		{"a-(b-c)", "a - (b - c)"},
+		{"(a-b)-c", "a - b - c"},
+		{"a/(b*c)", "a / (b * c)"},
+		{"2^3^2", "2^3^2"},
+		{"(2^3)^2", "(2^3)^2"},
+		{"-x^2", "-x^2"},
+		{"(-x)^2", "(-x)^2"},
+		{"a > b ? a : b", "a > b ? a : b"},
+		{"max(a, (b))", "max(a, b)"},
+	}
+	for _, tt := range tests {
+		if got := Format(mustParse(t, tt.src)); got != tt.want {
+			t.Errorf("Format(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		"1 - (2 - 3) - 4",
+		"a / b / (c / d)",
+		"-(-x)",
+		"2 ^ -x ^ 2",
+		"!(a && b) || c",
+		"a ? b ? 1 : 2 : c ? 3 : 4",
+		"(a ? b : c) + 1",
+		"x % (y * z)",
+		"1e-7 * 2.50",
+		`"a" + str`,
+		"sin(x)^2 + cos(x)^2",
+	} {
+		expr := mustParse(t, src)
+		s := Format(expr)
+		again, err := ParseExpression(s)
+		if err != nil {
+			t.Errorf("%s: Format = %s: %v", src, s, err)
+			continue
+		}
+		if !EqualExpr(expr, again) {
+			t.Errorf("%s: Format = %s does not round trip", src, s)
+		}
+	}
+}
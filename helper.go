@@ -33,101 +33,97 @@ func ErrPos(s string, pos int) string {
 	return r + s + r
 }
 
+// ErrSpan 在源码下方标记区间，如 1 + x / 0 中的 x / 0
+func ErrSpan(s string, span Span) string {
+	start, end := span.Start, span.End
+	if start < 0 {
+		start = 0
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	if end <= start {
+		return ErrPos(s, start)
+	}
+	r := strings.Repeat("-", len(s)) + "\n"
+	s += "\n" + strings.Repeat(" ", start) + "^" + strings.Repeat("~", end-start-1) + "\n"
+	return r + s + r
+}
+
+// ErrSource 在源码中标记运行时错误所在的子表达式，err未记录源码位置时仅返回错误信息
+func ErrSource(s string, err error) string {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) {
+		if span := evalErr.Span(); span.IsValid() {
+			return err.Error() + "\n" + ErrSpan(s, span)
+		}
+	}
+	return err.Error()
+}
+
 func Float64ToStr(f float64) string {
 	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
 // ParseExpression 解析表达式
 func ParseExpression(s string) (ExprNode, error) {
-	toks, err := Parse(s)
-	if err != nil {
-		return nil, err
-	}
-	ast := NewAST(toks, s)
-	if ast.Err != nil {
-		return nil, ast.Err
-	}
-	ar := ast.ParseExpression()
-	if ast.Err != nil {
-		return nil, ast.Err
-	}
-	defer func() {
-		if e := recover(); e != nil {
-			err = e.(error)
-		}
-	}()
-	return ar, nil
+	return defaultEnv.ParseExpression(s)
 }
 
 // RegDefFunc 注册函数
 func RegDefFunc(name string, df DefFunc) error {
-	if len(name) == 0 {
-		return errors.New("RegFunction name is not empty")
-	}
-	if df.Argc() < -1 {
-		return errors.New("RegFunction argc should be -1, 0, or a positive integer")
-	}
-	if _, ok := defFunc[name]; ok {
-		return errors.New("RegFunction name is already exist")
-	}
-	defFunc[name] = df
-	return nil
+	return defaultEnv.RegDefFunc(name, df)
 }
 
 // RegConst 注册全局常量
 func RegConst(name string, value float64) error {
-	if len(name) == 0 {
-		return errors.New("RegConst name is not empty")
-	}
-	if _, ok := defConst[name]; ok {
-		return errors.New("RegConst name is already exist")
-	}
-	defConst[name] = value
-	return nil
+	return defaultEnv.RegConst(name, value)
 }
 
 // RegConstLaTex 注册全局latex
 func RegConstLaTex(name string, value string) error {
-	if len(name) == 0 {
-		return errors.New("RegConstLaTex name is not empty")
-	}
-	if _, ok := defConstLaTex[name]; ok {
-		return errors.New("RegConstLaTex name is already exist")
-	}
-	defConstLaTex[name] = value
-	return nil
+	return defaultEnv.RegConstLaTex(name, value)
 }
 
 // GetDefFunc 获取函数
 func GetDefFunc(name string) DefFunc {
-	return defFunc[name]
+	f, _ := defaultEnv.Func(name)
+	return f
 }
 
 // GetOperator 获取操作单元
 func GetOperator(name byte) OperatorItem {
-	return Operators[name]
+	o, _ := defaultEnv.Operator(string(name))
+	return o
 }
 
 // GetDefConstLaTex 获取全局latex
 func GetDefConstLaTex(name string) string {
-	return defConstLaTex[name]
+	v, _ := defaultEnv.ConstLaTex(name)
+	return v
 }
 
 // GetDefConst 获取全局常量
 func GetDefConst(name string) float64 {
-	return defConst[name]
+	v, _ := defaultEnv.Const(name)
+	return v
 }
 
-// GetCtxParameter 解析上下文Parameter对象
+// GetCtxParameter 解析上下文Parameter对象，优先读取WithParameter设置的值
+// 兼容以字符串"parameter"为key设置的值，新代码请使用WithParameter
 func GetCtxParameter(ctx context.Context) (*Parameter, error) {
+	if parameter, ok := ctx.Value(paramCtxKey{}).(*Parameter); ok && parameter != nil {
+		return parameter, nil
+	}
+
 	value := ctx.Value("parameter")
 	if value == nil {
-		return nil, errors.New("no parameter found")
+		return nil, ErrNoParameter
 	}
 
 	parameter, exists := value.(*Parameter)
 	if !exists {
-		return nil, errors.New("no parameter found")
+		return nil, ErrNoParameter
 	}
 	return parameter, nil
 }
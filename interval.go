@@ -0,0 +1,714 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// IntervalOperator 区间运算，EvaluateInterval优先使用，未实现时仅支持单点区间，以float64计算
+type IntervalOperator interface {
+	IntervalResult(a Interval, b Interval) (Interval, error)
+}
+
+// IntervalFunc 以区间参数计算，EvaluateInterval优先使用，未实现时仅支持单点区间，以float64计算
+// 返回的区间须包含参数区间内所有取值的结果
+type IntervalFunc interface {
+	CallInterval(args []Interval) (Interval, error)
+}
+
+// Interval 闭区间 [Lo, Hi]，端点可以为±Inf
+type Interval struct {
+	Lo float64
+	Hi float64
+}
+
+// PointInterval 单点区间 [x, x]
+func PointInterval(x float64) Interval {
+	return Interval{Lo: x, Hi: x}
+}
+
+// IsPoint 是否为单点区间
+func (iv Interval) IsPoint() bool {
+	return iv.Lo == iv.Hi
+}
+
+// Contains 是否包含x
+func (iv Interval) Contains(x float64) bool {
+	return iv.Lo <= x && x <= iv.Hi
+}
+
+// Width 区间宽度
+func (iv Interval) Width() float64 {
+	return iv.Hi - iv.Lo
+}
+
+func (iv Interval) String() string {
+	return "[" + Float64ToStr(iv.Lo) + ", " + Float64ToStr(iv.Hi) + "]"
+}
+
+func (iv Interval) valid() bool {
+	return iv.Lo <= iv.Hi
+}
+
+// CalculateInterval 以区间计算，异常时panic，需要error返回请使用EvaluateInterval
+func CalculateInterval(expr ExprNode, ctx context.Context) Interval {
+	v, err := EvaluateInterval(ctx, expr)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// EvaluateInterval 计算表达式在变量取值区间内结果的范围，结果向外舍入，保证包含所有可能的取值
+// 变量值可以是Interval、[2]float64、长度为2的[]float64或数值，条件不确定时合并两个分支的结果
+func EvaluateInterval(ctx context.Context, expr ExprNode) (Interval, error) {
+	ev := newEvaluator(ctx)
+	return newNumEvaluator[Interval](ev, intervalArith{opts: ev.st.opts}).eval(expr)
+}
+
+// 有向舍入，err为真实值与x的差，符号决定是否需要调整到相邻的浮点数
+func roundDown(x float64, err float64) float64 {
+	if err < 0 {
+		return math.Nextafter(x, math.Inf(-1))
+	}
+	return x
+}
+
+func roundUp(x float64, err float64) float64 {
+	if err > 0 {
+		return math.Nextafter(x, math.Inf(1))
+	}
+	return x
+}
+
+// overflowErr 有限操作数的结果溢出为±Inf时，真实值位于Inf的内侧
+func overflowErr(x float64) float64 {
+	return -x
+}
+
+// addErr a+b的舍入误差
+func addErr(a float64, b float64, s float64) float64 {
+	if math.IsInf(s, 0) {
+		if math.IsInf(a, 0) || math.IsInf(b, 0) {
+			return 0
+		}
+		return overflowErr(s)
+	}
+	bb := s - a
+	return (a - (s - bb)) + (b - bb)
+}
+
+// mulErr a*b的舍入误差
+func mulErr(a float64, b float64, p float64) float64 {
+	switch {
+	case a == 0 || b == 0 || math.IsInf(a, 0) || math.IsInf(b, 0):
+		return 0
+	case math.IsInf(p, 0):
+		return overflowErr(p)
+	case math.Abs(p) < 0x1p-1022:
+		// 下溢时误差的符号不可靠，两个方向均扩展
+		return math.NaN()
+	}
+	return math.FMA(a, b, -p)
+}
+
+// quoErr a/b的舍入误差的符号
+func quoErr(a float64, b float64, q float64) float64 {
+	switch {
+	case a == 0 || math.IsInf(a, 0) || math.IsInf(b, 0):
+		return 0
+	case math.IsInf(q, 0):
+		return overflowErr(q)
+	case math.Abs(q) < 0x1p-1022:
+		return math.NaN()
+	}
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		return -r
+	}
+	return r
+}
+
+// NaN误差表示无法确定方向，两个方向均扩展
+func down(x float64, err float64) float64 {
+	if err != err {
+		return math.Nextafter(x, math.Inf(-1))
+	}
+	return roundDown(x, err)
+}
+
+func up(x float64, err float64) float64 {
+	if err != err {
+		return math.Nextafter(x, math.Inf(1))
+	}
+	return roundUp(x, err)
+}
+
+func addDown(a float64, b float64) float64 {
+	s := a + b
+	return down(s, addErr(a, b, s))
+}
+
+func addUp(a float64, b float64) float64 {
+	s := a + b
+	return up(s, addErr(a, b, s))
+}
+
+// mulDown 0与Inf的乘积按0计算
+func mulDown(a float64, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	return down(p, mulErr(a, b, p))
+}
+
+func mulUp(a float64, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	return up(p, mulErr(a, b, p))
+}
+
+func quoDown(a float64, b float64) float64 {
+	q := a / b
+	return down(q, quoErr(a, b, q))
+}
+
+func quoUp(a float64, b float64) float64 {
+	q := a / b
+	return up(q, quoErr(a, b, q))
+}
+
+// widen 非精确舍入的函数结果向外扩展n个ulp
+func widen(iv Interval, n int) Interval {
+	for i := 0; i < n; i++ {
+		if !math.IsInf(iv.Lo, 0) {
+			iv.Lo = math.Nextafter(iv.Lo, math.Inf(-1))
+		}
+		if !math.IsInf(iv.Hi, 0) {
+			iv.Hi = math.Nextafter(iv.Hi, math.Inf(1))
+		}
+	}
+	return iv
+}
+
+// clampInterval 限制在函数值域内
+func clampInterval(iv Interval, lo float64, hi float64) Interval {
+	return Interval{Lo: math.Max(iv.Lo, lo), Hi: math.Min(iv.Hi, hi)}
+}
+
+// hull 包含两个区间的最小区间
+func hull(a Interval, b Interval) Interval {
+	return Interval{Lo: math.Min(a.Lo, b.Lo), Hi: math.Max(a.Hi, b.Hi)}
+}
+
+var entireInterval = Interval{Lo: math.Inf(-1), Hi: math.Inf(1)}
+
+func intervalAdd(a Interval, b Interval) Interval {
+	return Interval{Lo: addDown(a.Lo, b.Lo), Hi: addUp(a.Hi, b.Hi)}
+}
+
+func intervalSub(a Interval, b Interval) Interval {
+	return Interval{Lo: addDown(a.Lo, -b.Hi), Hi: addUp(a.Hi, -b.Lo)}
+}
+
+func intervalMul(a Interval, b Interval) Interval {
+	return Interval{
+		Lo: math.Min(math.Min(mulDown(a.Lo, b.Lo), mulDown(a.Lo, b.Hi)), math.Min(mulDown(a.Hi, b.Lo), mulDown(a.Hi, b.Hi))),
+		Hi: math.Max(math.Max(mulUp(a.Lo, b.Lo), mulUp(a.Lo, b.Hi)), math.Max(mulUp(a.Hi, b.Lo), mulUp(a.Hi, b.Hi))),
+	}
+}
+
+// intervalDiv 除数区间包含0时结果为两段区间的并集，取其外包区间
+func intervalDiv(a Interval, b Interval) (Interval, error) {
+	if b.Lo == 0 && b.Hi == 0 {
+		return Interval{}, ErrDivisionByZero
+	}
+	if !b.Contains(0) {
+		return Interval{
+			Lo: math.Min(math.Min(quoDown(a.Lo, b.Lo), quoDown(a.Lo, b.Hi)), math.Min(quoDown(a.Hi, b.Lo), quoDown(a.Hi, b.Hi))),
+			Hi: math.Max(math.Max(quoUp(a.Lo, b.Lo), quoUp(a.Lo, b.Hi)), math.Max(quoUp(a.Hi, b.Lo), quoUp(a.Hi, b.Hi))),
+		}, nil
+	}
+	if a.Contains(0) || (b.Lo < 0 && b.Hi > 0) {
+		return entireInterval, nil
+	}
+	inf := math.Inf(1)
+	if b.Lo == 0 {
+		// 除数为 [0, hi]
+		if a.Hi < 0 {
+			return Interval{Lo: -inf, Hi: quoUp(a.Hi, b.Hi)}, nil
+		}
+		return Interval{Lo: quoDown(a.Lo, b.Hi), Hi: inf}, nil
+	}
+	// 除数为 [lo, 0]
+	if a.Hi < 0 {
+		return Interval{Lo: quoDown(a.Hi, b.Lo), Hi: inf}, nil
+	}
+	return Interval{Lo: -inf, Hi: quoUp(a.Lo, b.Lo)}, nil
+}
+
+// intervalMod 与Mod一致先将操作数截断为整数，结果符号与被除数一致且绝对值小于除数，端点均为整数无需向外舍入
+// 除数为单点且被除数区间未跨越除数的整数倍时结果为精确区间，否则取保守的外包区间：
+// 被除数非负时为 [0, min(trunc(a.Hi), |trunc(b)|.Hi - 1)]，非正时对称，跨越0时为两者的外包区间
+// 除数区间内截断为0的取值被忽略，仅在除数整个区间截断为0时返回ErrDivisionByZero
+func intervalMod(a Interval, b Interval) (Interval, error) {
+	if a.IsPoint() && b.IsPoint() {
+		v, err := (&Mod{}).Evaluate(a.Lo, b.Lo)
+		if err != nil {
+			return Interval{}, ErrDivisionByZero
+		}
+		return PointInterval(v), nil
+	}
+	if b.Lo > -1 && b.Hi < 1 {
+		return Interval{}, ErrDivisionByZero
+	}
+	lo, hi := math.Trunc(a.Lo), math.Trunc(a.Hi)
+	m := math.Max(math.Abs(math.Trunc(b.Lo)), math.Abs(math.Trunc(b.Hi))) - 1
+	if b.IsPoint() && (lo >= 0 || hi <= 0) && hi-lo < m+1 {
+		// 未跨越除数的整数倍时取余在区间上单调
+		ml, mh := math.Mod(lo, m+1), math.Mod(hi, m+1)
+		if ml <= mh {
+			return Interval{Lo: ml, Hi: mh}, nil
+		}
+	}
+	r := Interval{Lo: math.Max(lo, -m), Hi: math.Min(hi, m)}
+	if lo >= 0 {
+		r.Lo = 0
+	}
+	if hi <= 0 {
+		r.Hi = 0
+	}
+	return r, nil
+}
+
+// maxIntervalPowSplit 负底数配合区间指数时逐个计算的最大整数指数个数，超过时返回整个实数轴
+const maxIntervalPowSplit = 64
+
+// intervalPow 整数次幂按奇偶性计算，非整数次幂要求底数非负
+// 底数包含负数且指数不是单点区间时，负数部分仅在整数指数处有定义，按指数区间内的各个整数分别计算后合并
+func intervalPow(a Interval, b Interval) (Interval, error) {
+	if b.IsPoint() && b.Lo == math.Trunc(b.Lo) && math.Abs(b.Lo) <= 1<<53 {
+		return intervalIntPow(a, int64(b.Lo))
+	}
+	r := Interval{Lo: math.Inf(1), Hi: math.Inf(-1)}
+	if a.Lo < 0 {
+		lo, hi := math.Ceil(b.Lo), math.Floor(b.Hi)
+		if lo > hi {
+			if a.Hi < 0 {
+				return Interval{}, errors.New(fmt.Sprintf("negative base %s with non-integer exponent %s", a, b))
+			}
+		} else {
+			if hi-lo >= maxIntervalPowSplit || math.Abs(lo) > 1<<53 || math.Abs(hi) > 1<<53 {
+				return entireInterval, nil
+			}
+			neg := Interval{Lo: a.Lo, Hi: math.Min(a.Hi, 0)}
+			for n := int64(lo); n <= int64(hi); n++ {
+				p, err := intervalIntPow(neg, n)
+				if err != nil {
+					return Interval{}, err
+				}
+				r = hull(r, p)
+			}
+		}
+		if a.Hi < 0 {
+			return r, nil
+		}
+		a.Lo = 0
+	}
+	// 非负底数的幂对底数及指数分别单调，极值在端点处取得
+	p := Interval{Lo: math.Inf(1), Hi: math.Inf(-1)}
+	for _, x := range []float64{a.Lo, a.Hi} {
+		for _, y := range []float64{b.Lo, b.Hi} {
+			p = hull(p, PointInterval(math.Pow(x, y)))
+		}
+	}
+	return hull(r, clampInterval(widen(p, 2), 0, math.Inf(1))), nil
+}
+
+func intervalIntPow(a Interval, n int64) (Interval, error) {
+	if n == 0 {
+		return PointInterval(1), nil
+	}
+	if n < 0 {
+		p, err := intervalIntPow(a, -n)
+		if err != nil {
+			return p, err
+		}
+		return intervalDiv(PointInterval(1), p)
+	}
+	if n%2 == 1 {
+		// 奇数次幂单调递增
+		return Interval{Lo: signedPowDown(a.Lo, n), Hi: signedPowUp(a.Hi, n)}, nil
+	}
+	lo, hi := math.Abs(a.Lo), math.Abs(a.Hi)
+	switch {
+	case a.Lo >= 0:
+		return Interval{Lo: powDown(lo, n), Hi: powUp(hi, n)}, nil
+	case a.Hi <= 0:
+		return Interval{Lo: powDown(hi, n), Hi: powUp(lo, n)}, nil
+	}
+	// 偶数次幂在包含0的区间上最小值为0
+	return Interval{Lo: 0, Hi: powUp(math.Max(lo, hi), n)}, nil
+}
+
+// powDown 非负数x的n次幂的下界
+func powDown(x float64, n int64) float64 {
+	r := 1.0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r = mulDown(r, x)
+		}
+		x = mulDown(x, x)
+	}
+	return r
+}
+
+func powUp(x float64, n int64) float64 {
+	r := 1.0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r = mulUp(r, x)
+		}
+		x = mulUp(x, x)
+	}
+	return r
+}
+
+// 奇数次幂保持符号
+func signedPowDown(x float64, n int64) float64 {
+	if x < 0 {
+		return -powUp(-x, n)
+	}
+	return powDown(x, n)
+}
+
+func signedPowUp(x float64, n int64) float64 {
+	if x < 0 {
+		return -powDown(-x, n)
+	}
+	return powUp(x, n)
+}
+
+// 区间的真值，区间不含0时为真，[0, 0]为假，其他情况不确定
+var (
+	trueInterval    = PointInterval(1)
+	falseInterval   = PointInterval(0)
+	unknownInterval = Interval{Lo: 0, Hi: 1}
+)
+
+func certainTrue(iv Interval) bool {
+	return !iv.Contains(0)
+}
+
+func certainFalse(iv Interval) bool {
+	return iv.Lo == 0 && iv.Hi == 0
+}
+
+func truthInterval(t bool, f bool) Interval {
+	switch {
+	case t:
+		return trueInterval
+	case f:
+		return falseInterval
+	}
+	return unknownInterval
+}
+
+// intervalArith 区间计算
+type intervalArith struct {
+	opts Options
+}
+
+// literal 字面量按源码文本向外舍入，0.1等无法精确表示的值得到包含真实值的相邻浮点数区间
+func (ar intervalArith) literal(n NumberExprNode) (Interval, error) {
+	if n.Str != "" {
+		lo, _, err1 := big.ParseFloat(n.Str, 0, 53, big.ToNegativeInf)
+		hi, _, err2 := big.ParseFloat(n.Str, 0, 53, big.ToPositiveInf)
+		if err1 == nil && err2 == nil {
+			l, _ := lo.Float64()
+			h, _ := hi.Float64()
+			return Interval{Lo: l, Hi: h}, nil
+		}
+	}
+	return PointInterval(n.Val), nil
+}
+
+func (ar intervalArith) convert(value any) (Interval, bool) {
+	var iv Interval
+	switch t := value.(type) {
+	case Interval:
+		iv = t
+	case [2]float64:
+		iv = Interval{Lo: t[0], Hi: t[1]}
+	case []float64:
+		if len(t) != 2 {
+			return iv, false
+		}
+		iv = Interval{Lo: t[0], Hi: t[1]}
+	case float64:
+		iv = PointInterval(t)
+	case float32:
+		iv = PointInterval(float64(t))
+	default:
+		return iv, false
+	}
+	return iv, iv.valid()
+}
+
+// fromFloat 常量及float64计算的结果，超出整数精确范围时向外扩展1ulp
+func (ar intervalArith) fromFloat(f float64) (Interval, error) {
+	if f != f {
+		return Interval{}, ErrNotFinite
+	}
+	if f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+		return PointInterval(f), nil
+	}
+	return widen(PointInterval(f), 1), nil
+}
+
+func (ar intervalArith) toFloat(v Interval) float64 {
+	return v.Lo
+}
+
+func (ar intervalArith) truth(v Interval) (bool, error) {
+	return certainTrue(v), nil
+}
+
+func (ar intervalArith) uncertain(c Interval) bool {
+	return !certainTrue(c) && !certainFalse(c)
+}
+
+func (ar intervalArith) join(a Interval, b Interval) Interval {
+	return hull(a, b)
+}
+
+func (ar intervalArith) shortCircuit(operator OperatorItem, op string, a Interval) (Interval, bool, error) {
+	switch operator.(type) {
+	case *And:
+		if certainFalse(a) {
+			return falseInterval, true, nil
+		}
+	case *Or:
+		if certainTrue(a) {
+			return trueInterval, true, nil
+		}
+	default:
+		if s, ok := operator.(ShortCircuitOperator); ok && a.IsPoint() {
+			if v, ok := s.ShortCircuit(a.Lo); ok {
+				iv, err := ar.fromFloat(v)
+				return iv, true, err
+			}
+		}
+	}
+	return Interval{}, false, nil
+}
+
+func (ar intervalArith) operator(operator OperatorItem, op string, a Interval, b Interval) (Interval, bool, error) {
+	if o, ok := operator.(IntervalOperator); ok {
+		v, err := o.IntervalResult(a, b)
+		if err != nil {
+			return v, true, &OperatorError{Op: op, Lhs: a.Lo, Rhs: b.Lo, Err: err}
+		}
+		return v, true, nil
+	}
+	switch operator.(type) {
+	case *Less:
+		return truthInterval(a.Hi < b.Lo, a.Lo >= b.Hi), true, nil
+	case *LessEqual:
+		return truthInterval(a.Hi <= b.Lo, a.Lo > b.Hi), true, nil
+	case *Greater:
+		return truthInterval(a.Lo > b.Hi, a.Hi <= b.Lo), true, nil
+	case *GreaterEqual:
+		return truthInterval(a.Lo >= b.Hi, a.Hi < b.Lo), true, nil
+	case *Equal:
+		return truthInterval(a.IsPoint() && a == b, a.Hi < b.Lo || b.Hi < a.Lo), true, nil
+	case *NotEqual:
+		return truthInterval(a.Hi < b.Lo || b.Hi < a.Lo, a.IsPoint() && a == b), true, nil
+	case *And:
+		return truthInterval(certainTrue(a) && certainTrue(b), certainFalse(a) || certainFalse(b)), true, nil
+	case *Or:
+		return truthInterval(certainTrue(a) || certainTrue(b), certainFalse(a) && certainFalse(b)), true, nil
+	case *Not:
+		return truthInterval(certainFalse(b), certainTrue(b)), true, nil
+	}
+	if a.IsPoint() && b.IsPoint() {
+		return Interval{}, false, nil
+	}
+	return Interval{}, true, errors.New(fmt.Sprintf("operator `%s` does not support intervals %s and %s", op, a, b))
+}
+
+func (ar intervalArith) unary(operator OperatorItem, op string, a Interval) (Interval, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		return Interval{Lo: -a.Hi, Hi: -a.Lo}, true, nil
+	case *Plus:
+		return a, true, nil
+	case *Not:
+		return truthInterval(certainFalse(a), certainTrue(a)), true, nil
+	}
+	if a.IsPoint() {
+		return Interval{}, false, nil
+	}
+	return Interval{}, true, errors.New(fmt.Sprintf("operator `%s` does not support interval %s", op, a))
+}
+
+func (ar intervalArith) call(def DefFunc, name string, args []Interval) (Interval, bool, error) {
+	if fn := intervalFunc(def); fn != nil {
+		kind := funcAngle(def)
+		if kind == angleArg && ar.opts.AngleUnit != Radian {
+			for i, a := range args {
+				args[i] = widen(Interval{Lo: ar.opts.AngleUnit.ToRadian(a.Lo), Hi: ar.opts.AngleUnit.ToRadian(a.Hi)}, 1)
+			}
+		}
+		v, err := fn(args)
+		if err == nil && kind == angleResult && ar.opts.AngleUnit != Radian {
+			v = widen(Interval{Lo: ar.opts.AngleUnit.FromRadian(v.Lo), Hi: ar.opts.AngleUnit.FromRadian(v.Hi)}, 1)
+		}
+		return v, true, err
+	}
+	for _, a := range args {
+		if !a.IsPoint() {
+			return Interval{}, true, errors.New(fmt.Sprintf("interval argument %s is not supported", a))
+		}
+	}
+	return Interval{}, false, nil
+}
+
+// intervalFunc 获取函数的区间实现
+func intervalFunc(def DefFunc) func(args []Interval) (Interval, error) {
+	switch t := def.(type) {
+	case IntervalFunc:
+		return t.CallInterval
+	case *stdFunc:
+		return t.interval
+	case *stdDiffFunc:
+		return t.interval
+	}
+	return nil
+}
+
+// withInterval 设置标准库函数的区间实现
+func withInterval(fn func(args []Interval) (Interval, error), f DefFunc) DefFunc {
+	switch t := f.(type) {
+	case *stdFunc:
+		t.interval = fn
+	case *stdDiffFunc:
+		t.interval = fn
+	}
+	return f
+}
+
+// intervalMonotone 单调函数的区间实现，math库函数的误差按2ulp向外扩展并限制在值域[min, max]内
+func intervalMonotone(fn func(float64) float64, increasing bool, min float64, max float64) func(args []Interval) (Interval, error) {
+	return func(args []Interval) (Interval, error) {
+		lo, hi := fn(args[0].Lo), fn(args[0].Hi)
+		if !increasing {
+			lo, hi = hi, lo
+		}
+		if lo != lo || hi != hi {
+			return Interval{}, errors.New(fmt.Sprintf("interval %s is out of domain", args[0]))
+		}
+		return clampInterval(widen(Interval{Lo: lo, Hi: hi}, 2), min, max), nil
+	}
+}
+
+// intervalDomain 限制参数在定义域内，参数区间与定义域不相交时返回错误
+func intervalDomain(fn func(args []Interval) (Interval, error), lo float64, hi float64) func(args []Interval) (Interval, error) {
+	return func(args []Interval) (Interval, error) {
+		a := clampInterval(args[0], lo, hi)
+		if !a.valid() {
+			return Interval{}, errors.New(fmt.Sprintf("interval %s is out of domain", args[0]))
+		}
+		return fn([]Interval{a})
+	}
+}
+
+// intervalExact 精确舍入的单调递增函数，如取整
+func intervalExact(fn func(float64) float64) func(args []Interval) (Interval, error) {
+	return func(args []Interval) (Interval, error) {
+		return Interval{Lo: fn(args[0].Lo), Hi: fn(args[0].Hi)}, nil
+	}
+}
+
+func intervalAbs(args []Interval) (Interval, error) {
+	a := args[0]
+	switch {
+	case a.Lo >= 0:
+		return a, nil
+	case a.Hi <= 0:
+		return Interval{Lo: -a.Hi, Hi: -a.Lo}, nil
+	}
+	return Interval{Lo: 0, Hi: math.Max(-a.Lo, a.Hi)}, nil
+}
+
+// intervalSqrt 平方根为正确舍入，按余数的符号调整端点
+func intervalSqrt(args []Interval) (Interval, error) {
+	a := args[0]
+	sqrt := func(x float64) (float64, float64) {
+		s := math.Sqrt(x)
+		if x == 0 || math.IsInf(x, 0) {
+			return s, 0
+		}
+		return s, math.FMA(-s, s, x)
+	}
+	lo, errLo := sqrt(a.Lo)
+	hi, errHi := sqrt(a.Hi)
+	return Interval{Lo: math.Max(roundDown(lo, errLo), 0), Hi: roundUp(hi, errHi)}, nil
+}
+
+func intervalPowFunc(args []Interval) (Interval, error) {
+	return intervalPow(args[0], args[1])
+}
+
+func intervalMin(args []Interval) (Interval, error) {
+	r := args[0]
+	for _, a := range args[1:] {
+		r = Interval{Lo: math.Min(r.Lo, a.Lo), Hi: math.Min(r.Hi, a.Hi)}
+	}
+	return r, nil
+}
+
+func intervalMax(args []Interval) (Interval, error) {
+	r := args[0]
+	for _, a := range args[1:] {
+		r = Interval{Lo: math.Max(r.Lo, a.Lo), Hi: math.Max(r.Hi, a.Hi)}
+	}
+	return r, nil
+}
+
+// intervalSin 端点值之外，区间包含极值点π/2+2kπ、-π/2+2kπ时取到±1
+func intervalSin(args []Interval) (Interval, error) {
+	return periodic(args[0], math.Sin, math.Pi/2)
+}
+
+// intervalCos 极值点为2kπ、π+2kπ
+func intervalCos(args []Interval) (Interval, error) {
+	return periodic(args[0], math.Cos, 0)
+}
+
+// periodic 周期为2π、极大值点为peak+2kπ、极小值点为peak+π+2kπ的函数
+func periodic(a Interval, fn func(float64) float64, peak float64) (Interval, error) {
+	if math.IsInf(a.Lo, 0) || math.IsInf(a.Hi, 0) || a.Width() >= 2*math.Pi {
+		return Interval{Lo: -1, Hi: 1}, nil
+	}
+	r := widen(hull(PointInterval(fn(a.Lo)), PointInterval(fn(a.Hi))), 2)
+	// 极值点的计算存在舍入误差，放宽判断范围，多包含极值只会使结果更保守
+	tol := (math.Abs(a.Lo) + math.Abs(a.Hi) + 1) * 1e-15
+	contains := func(c float64) bool {
+		k := math.Ceil((a.Lo - tol - c) / (2 * math.Pi))
+		return c+2*math.Pi*k <= a.Hi+tol
+	}
+	if contains(peak) {
+		r.Hi = 1
+	}
+	if contains(peak + math.Pi) {
+		r.Lo = -1
+	}
+	return clampInterval(r, -1, 1), nil
+}
@@ -0,0 +1,164 @@
+package mathastc
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEvaluateInterval(t *testing.T) {
+	ctx := varsCtx(map[string]any{"x": Interval{Lo: -1, Hi: 2}, "y": [2]float64{1, 3}, "p": 2})
+	tests := []struct {
+		src  string
+		want Interval
+	}{
+		{"x + y", Interval{0, 5}},
+		{"x - y", Interval{-4, 1}},
+		{"x * y", Interval{-3, 6}},
+		{"x / y", Interval{-1, 2}},
+		{"x ^ 2", Interval{0, 4}},
+		{"abs(x)", Interval{0, 2}},
+		{"p * 3", Interval{6, 6}},
+		{"x > 0 ? 1 : 2", Interval{1, 2}},
+		{"y > 0 ? 1 : 2", Interval{1, 1}},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateInterval(ctx, mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateIntervalOutward(t *testing.T) {
+	got, err := EvaluateInterval(varsCtx(nil), mustParse(t, "0.1 + 0.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Contains(0.30000000000000004) || got.IsPoint() || got.Width() > 1e-15 {
+		t.Errorf("0.1 + 0.2 = %s", got)
+	}
+}
+
+func TestEvaluateIntervalErrors(t *testing.T) {
+	ctx := varsCtx(map[string]any{"x": Interval{Lo: -1, Hi: 1}, "y": Interval{Lo: 2, Hi: 3}})
+	if _, err := EvaluateInterval(ctx, mustParse(t, "x / 0")); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("x / 0: %v", err)
+	}
+	// 除数区间包含0时结果为整个实数轴
+	if iv, err := EvaluateInterval(ctx, mustParse(t, "1 / x")); err != nil || !math.IsInf(iv.Lo, -1) || !math.IsInf(iv.Hi, 1) {
+		t.Errorf("1 / x = %s, %v", iv, err)
+	}
+	for _, src := range []string{"sqrt(x - 2)", "asin(y)", "(x - 2) ^ 0.5"} {
+		if _, err := EvaluateInterval(ctx, mustParse(t, src)); err == nil {
+			t.Errorf("%s: want error", src)
+		}
+	}
+}
+
+// TestEvaluateIntervalContainment 随机取样校验区间结果包含所有取值
+func TestEvaluateIntervalContainment(t *testing.T) {
+	exprs := []ExprNode{
+		mustParse(t, "x * y - x / (y + 4)"),
+		mustParse(t, "sin(x) * cos(y) + exp(x / 3)"),
+		mustParse(t, "(x - y) ^ 3 + abs(x) * sqrt(y + 3)"),
+		mustParse(t, "max(x, y) - min(x * y, 1) + atan(x)"),
+		mustParse(t, "(x * 5) % (y + 4) + (y * 7) % 3"),
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		x := Interval{Lo: r.Float64()*4 - 2}
+		x.Hi = x.Lo + r.Float64()
+		y := Interval{Lo: r.Float64()*4 - 2}
+		y.Hi = y.Lo + r.Float64()
+		ctx := varsCtx(map[string]any{"x": x, "y": y})
+		for _, expr := range exprs {
+			iv, err := EvaluateInterval(ctx, expr)
+			if err != nil {
+				t.Fatalf("%s: %v", Format(expr), err)
+			}
+			for j := 0; j < 20; j++ {
+				vx := x.Lo + r.Float64()*(x.Hi-x.Lo)
+				vy := y.Lo + r.Float64()*(y.Hi-y.Lo)
+				v, err := Evaluate(varsCtx(map[string]any{"x": vx, "y": vy}), expr)
+				if err != nil || math.IsNaN(v) {
+					continue
+				}
+				if !iv.Contains(v) {
+					t.Fatalf("%s at x=%v y=%v: %v not in %s", Format(expr), vx, vy, v, iv)
+				}
+			}
+		}
+	}
+}
+
+// 负底数配合区间指数时，负数部分在指数区间内的整数处取值
+func TestEvaluateIntervalNegativeBasePow(t *testing.T) {
+	tests := []struct {
+		x, y Interval
+		want Interval
+	}{
+		{Interval{-2, 1}, Interval{1, 3}, Interval{-8, 4}},
+		{Interval{-2, -1}, Interval{1.5, 2.5}, Interval{1, 4}},
+	}
+	for _, tt := range tests {
+		ctx := varsCtx(map[string]any{"x": tt.x, "y": tt.y})
+		got, err := EvaluateInterval(ctx, mustParse(t, "x ^ y"))
+		if err != nil {
+			t.Fatalf("x=%s y=%s: %v", tt.x, tt.y, err)
+		}
+		if got.Lo > tt.want.Lo || got.Hi < tt.want.Hi {
+			t.Errorf("x=%s y=%s: x ^ y = %s, want to contain %s", tt.x, tt.y, got, tt.want)
+		}
+		for _, vx := range []float64{tt.x.Lo, tt.x.Hi} {
+			for n := math.Ceil(tt.y.Lo); n <= tt.y.Hi; n++ {
+				if v := math.Pow(vx, n); !got.Contains(v) {
+					t.Errorf("x=%s y=%s: %v^%v = %v not in %s", tt.x, tt.y, vx, n, v, got)
+				}
+			}
+		}
+	}
+	ctx := varsCtx(map[string]any{"x": Interval{-2, -1}, "y": Interval{0.2, 0.8}})
+	if _, err := EvaluateInterval(ctx, mustParse(t, "x ^ y")); err == nil {
+		t.Error("negative base without integer exponent: want error")
+	}
+	ctx = varsCtx(map[string]any{"x": Interval{-2, 1}, "y": Interval{0, 1000}})
+	if iv, err := EvaluateInterval(ctx, mustParse(t, "x ^ y")); err != nil || iv != entireInterval {
+		t.Errorf("wide exponent = %s, %v", iv, err)
+	}
+}
+
+func TestEvaluateIntervalMod(t *testing.T) {
+	tests := []struct {
+		x, y Interval
+		want Interval
+	}{
+		{Interval{5, 6.5}, PointInterval(4), Interval{1, 2}},
+		{Interval{5, 9}, PointInterval(4), Interval{0, 3}},
+		{Interval{-6, -5}, PointInterval(4), Interval{-2, -1}},
+		{Interval{-4, -2}, PointInterval(3), Interval{-2, 0}},
+		{Interval{-1, 2}, Interval{2, 3}, Interval{-1, 2}},
+		{Interval{-10, 10}, Interval{-3, 0.5}, Interval{-2, 2}},
+		{PointInterval(7), PointInterval(3), PointInterval(1)},
+	}
+	for _, tt := range tests {
+		ctx := varsCtx(map[string]any{"x": tt.x, "y": tt.y})
+		got, err := EvaluateInterval(ctx, mustParse(t, "x % y"))
+		if err != nil {
+			t.Errorf("x=%s y=%s: %v", tt.x, tt.y, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("x=%s y=%s: x %% y = %s, want %s", tt.x, tt.y, got, tt.want)
+		}
+	}
+	ctx := varsCtx(map[string]any{"x": Interval{1, 5}, "y": Interval{-0.5, 0.5}})
+	if _, err := EvaluateInterval(ctx, mustParse(t, "x % y")); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("x %% y with |y| < 1: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package mathastc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ExprJSONVersion 表达式JSON格式版本
+const ExprJSONVersion = 1
+
+// 节点类型标记
+const (
+	jsonNumber      = "number"
+	jsonOperator    = "operator"
+	jsonUnary       = "unary"
+	jsonCall        = "call"
+	jsonVariable    = "variable"
+	jsonConditional = "conditional"
+	jsonConst       = "const"
+	jsonString      = "string"
+)
+
+// jsonExpr 节点的JSON表示，以type区分节点类型
+type jsonExpr struct {
+	Type    string     `json:"type"`
+	Op      string     `json:"op,omitempty"`
+	Name    string     `json:"name,omitempty"`
+	Value   *jsonFloat `json:"value,omitempty"`
+	Str     string     `json:"str,omitempty"`
+	Lhs     ExprNode   `json:"lhs,omitempty"`
+	Rhs     ExprNode   `json:"rhs,omitempty"`
+	Operand ExprNode   `json:"operand,omitempty"`
+	Args    []ExprNode `json:"args,omitempty"`
+	Cond    ExprNode   `json:"cond,omitempty"`
+	Then    ExprNode   `json:"then,omitempty"`
+	Else    ExprNode   `json:"else,omitempty"`
+	Span    []int      `json:"span,omitempty"`
+}
+
+// jsonFloat 数值的JSON表示，NaN及±Inf以字符串"NaN"、"+Inf"、"-Inf"表示
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	switch {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(v, 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Inf"`), nil
+	}
+	return json.Marshal(v)
+}
+
+func (f *jsonFloat) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var v float64
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*f = jsonFloat(v)
+		return nil
+	}
+	switch s {
+	case "NaN":
+		*f = jsonFloat(math.NaN())
+	case "+Inf":
+		*f = jsonFloat(math.Inf(1))
+	case "-Inf":
+		*f = jsonFloat(math.Inf(-1))
+	default:
+		return errors.New(fmt.Sprintf("invalid number value %q", s))
+	}
+	return nil
+}
+
+// rawExpr 解析时的节点表示，子节点延迟解析
+type rawExpr struct {
+	Type    string            `json:"type"`
+	Op      string            `json:"op"`
+	Name    string            `json:"name"`
+	Value   *jsonFloat        `json:"value"`
+	Str     string            `json:"str"`
+	Lhs     json.RawMessage   `json:"lhs"`
+	Rhs     json.RawMessage   `json:"rhs"`
+	Operand json.RawMessage   `json:"operand"`
+	Args    []json.RawMessage `json:"args"`
+	Cond    json.RawMessage   `json:"cond"`
+	Then    json.RawMessage   `json:"then"`
+	Else    json.RawMessage   `json:"else"`
+	Span    []int             `json:"span"`
+}
+
+// exprDocument 带版本的表达式JSON
+type exprDocument struct {
+	Version int             `json:"version"`
+	Expr    json.RawMessage `json:"expr"`
+}
+
+// MarshalExpr 序列化表达式，结果包含格式版本
+func MarshalExpr(expr ExprNode) ([]byte, error) {
+	data, err := json.Marshal(expr)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(exprDocument{Version: ExprJSONVersion, Expr: data})
+}
+
+// UnmarshalExpr 反序列化MarshalExpr生成的表达式，并校验函数、常量及操作符
+func UnmarshalExpr(data []byte) (ExprNode, error) {
+	return defaultEnv.UnmarshalExpr(data)
+}
+
+// UnmarshalExpr 在当前环境中反序列化表达式
+func (e *Environment) UnmarshalExpr(data []byte) (ExprNode, error) {
+	var doc exprDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != ExprJSONVersion {
+		return nil, errors.New(fmt.Sprintf("unsupported expression version %d", doc.Version))
+	}
+	return e.decodeExpr(doc.Expr, "expr")
+}
+
+func (e *Environment) decodeExpr(data json.RawMessage, path string) (ExprNode, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, errors.New(fmt.Sprintf("%s: missing expression", path))
+	}
+	var raw rawExpr
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %v", path, err))
+	}
+	span := Span{}
+	if len(raw.Span) == 2 {
+		span = Span{raw.Span[0], raw.Span[1]}
+	}
+
+	switch raw.Type {
+	case jsonNumber:
+		if raw.Value == nil {
+			return nil, errors.New(fmt.Sprintf("%s: number without value", path))
+		}
+		return NumberExprNode{Val: float64(*raw.Value), Str: raw.Str, Span: span}, nil
+
+	case jsonVariable:
+		if raw.Name == "" {
+			return nil, errors.New(fmt.Sprintf("%s: variable without name", path))
+		}
+		return VariableExprNode{Val: raw.Name, Span: span}, nil
+
+	case jsonString:
+		return StringExprNode{Val: raw.Str, Span: span}, nil
+
+	case jsonConst:
+		v, ok := e.Const(raw.Name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("%s: const `%s` is undefined", path, raw.Name))
+		}
+		return ConstExprNode{Name: raw.Name, Val: v, Str: raw.Str, Span: span}, nil
+
+	case jsonOperator:
+		o, ok := e.Operator(raw.Op)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("%s: operator `%s` is undefined", path, raw.Op))
+		}
+		if isBracket(o) {
+			return nil, errors.New(fmt.Sprintf("%s: `%s` is not an operator", path, raw.Op))
+		}
+		lhs, err := e.decodeExpr(raw.Lhs, path+".lhs")
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := e.decodeExpr(raw.Rhs, path+".rhs")
+		if err != nil {
+			return nil, err
+		}
+		return OperatorExprNode{Op: raw.Op, Lhs: lhs, Rhs: rhs, Span: span}, nil
+
+	case jsonUnary:
+		o, _ := e.Operator(raw.Op)
+		if _, ok := o.(UnaryOperatorItem); !ok || isBracket(o) {
+			return nil, errors.New(fmt.Sprintf("%s: unary operator `%s` is undefined", path, raw.Op))
+		}
+		operand, err := e.decodeExpr(raw.Operand, path+".operand")
+		if err != nil {
+			return nil, err
+		}
+		return UnaryExprNode{Op: raw.Op, Operand: operand, Span: span}, nil
+
+	case jsonCall:
+		def, ok := e.Func(raw.Name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("%s: function `%s` is undefined", path, raw.Name))
+		}
+		if def.Argc() >= 0 && def.Argc() != len(raw.Args) {
+			return nil, errors.New(fmt.Sprintf("%s: function `%s` parameters want %d but get %d",
+				path, raw.Name, def.Argc(), len(raw.Args)))
+		}
+		// 标准库的可变参数函数校验参数个数范围
+		if f, ok := def.(interface{ checkArgc(n int) error }); ok {
+			if err := f.checkArgc(len(raw.Args)); err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: function `%s` %v", path, raw.Name, err))
+			}
+		}
+		args := make([]ExprNode, len(raw.Args))
+		for i, arg := range raw.Args {
+			a, err := e.decodeExpr(arg, path+".args["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			args[i] = a
+		}
+		return FunCallerExprNode{Name: raw.Name, Arg: args, Span: span}, nil
+
+	case jsonConditional:
+		c, err := e.decodeExpr(raw.Cond, path+".cond")
+		if err != nil {
+			return nil, err
+		}
+		t, err := e.decodeExpr(raw.Then, path+".then")
+		if err != nil {
+			return nil, err
+		}
+		f, err := e.decodeExpr(raw.Else, path+".else")
+		if err != nil {
+			return nil, err
+		}
+		return ConditionalExprNode{Cond: c, Then: t, Else: f, Span: span}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("%s: unknown expression type `%s`", path, raw.Type))
+}
+
+// isBracket 括号仅用于解析，不能作为操作符节点
+func isBracket(o OperatorItem) bool {
+	switch o.(type) {
+	case *LBrackets, *RBrackets:
+		return true
+	}
+	return false
+}
+
+func jsonSpan(span Span) []int {
+	if !span.IsValid() {
+		return nil
+	}
+	return []int{span.Start, span.End}
+}
+
+func (n NumberExprNode) MarshalJSON() ([]byte, error) {
+	v := jsonFloat(n.Val)
+	return json.Marshal(jsonExpr{Type: jsonNumber, Value: &v, Str: n.Str, Span: jsonSpan(n.Span)})
+}
+
+func (o OperatorExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonOperator, Op: o.Op, Lhs: o.Lhs, Rhs: o.Rhs, Span: jsonSpan(o.Span)})
+}
+
+func (u UnaryExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonUnary, Op: u.Op, Operand: u.Operand, Span: jsonSpan(u.Span)})
+}
+
+func (f FunCallerExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonCall, Name: f.Name, Args: f.Arg, Span: jsonSpan(f.Span)})
+}
+
+func (v VariableExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonVariable, Name: v.Val, Span: jsonSpan(v.Span)})
+}
+
+func (c ConditionalExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonConditional, Cond: c.Cond, Then: c.Then, Else: c.Else, Span: jsonSpan(c.Span)})
+}
+
+func (s StringExprNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonExpr{Type: jsonString, Str: s.Val, Span: jsonSpan(s.Span)})
+}
+
+func (c ConstExprNode) MarshalJSON() ([]byte, error) {
+	v := jsonFloat(c.Val)
+	return json.Marshal(jsonExpr{Type: jsonConst, Name: c.Name, Value: &v, Str: c.Str, Span: jsonSpan(c.Span)})
+}
+
+// unmarshalNode 使用默认环境解析节点，并校验节点类型
+func unmarshalNode[T ExprNode](data []byte, node *T) error {
+	expr, err := defaultEnv.decodeExpr(data, "expr")
+	if err != nil {
+		return err
+	}
+	t, ok := expr.(T)
+	if !ok {
+		return errors.New(fmt.Sprintf("want %T but get %T", *node, expr))
+	}
+	*node = t
+	return nil
+}
+
+func (n *NumberExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, n)
+}
+
+func (o *OperatorExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, o)
+}
+
+func (u *UnaryExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, u)
+}
+
+func (f *FunCallerExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, f)
+}
+
+func (v *VariableExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, v)
+}
+
+func (c *ConditionalExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, c)
+}
+
+func (c *ConstExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, c)
+}
+
+func (s *StringExprNode) UnmarshalJSON(data []byte) error {
+	return unmarshalNode(data, s)
+}
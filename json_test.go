@@ -0,0 +1,72 @@
+package mathastc
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMarshalExprRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		"1 + 2 * x",
+		"-x ^ 2",
+		"max(a, b, 3) > 1 ? pi : e",
+		`len("abc") + 1`,
+		"sin(x) / cos(y) % 2",
+	} {
+		expr := mustParse(t, src)
+		data, err := MarshalExpr(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		got, err := UnmarshalExpr(data)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", src, err, data)
+		}
+		if !EqualExpr(expr, got) {
+			t.Errorf("%s: round trip got %s", src, Format(got))
+		}
+	}
+}
+
+func TestMarshalExprNonFinite(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		data, err := MarshalExpr(newBinary("+", VariableExprNode{Val: "x"}, newNumber(v)))
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		expr, err := UnmarshalExpr(data)
+		if err != nil {
+			t.Fatalf("%v: %v\n%s", v, err, data)
+		}
+		got := expr.(OperatorExprNode).Rhs.(NumberExprNode).Val
+		if !(math.IsNaN(v) && math.IsNaN(got)) && got != v {
+			t.Errorf("round trip %v got %v", v, got)
+		}
+	}
+	var n NumberExprNode
+	if err := json.Unmarshal([]byte(`{"type":"number","value":"Infinity"}`), &n); err == nil {
+		t.Error("want error for invalid number value")
+	}
+}
+
+func TestUnmarshalExprInvalid(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"version":1,"expr":{"type":"operator","op":"(","lhs":{"type":"number","value":1},"rhs":{"type":"number","value":2}}}`, "not an operator"},
+		{`{"version":1,"expr":{"type":"unary","op":")","operand":{"type":"number","value":1}}}`, "undefined"},
+		{`{"version":1,"expr":{"type":"operator","op":"@","lhs":{"type":"number","value":1},"rhs":{"type":"number","value":2}}}`, "undefined"},
+		{`{"version":1,"expr":{"type":"call","name":"sin","args":[]}}`, "parameters"},
+		{`{"version":1,"expr":{"type":"number"}}`, "without value"},
+		{`{"version":2,"expr":{"type":"number","value":1}}`, "version"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalExpr([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package mathastc
+
+import (
+	"context"
+	"strings"
+)
+
+// 希腊字母
+var greekLetters = map[string]string{
+	"alpha":   "\\alpha",
+	"beta":    "\\beta",
+	"gamma":   "\\gamma",
+	"delta":   "\\delta",
+	"epsilon": "\\epsilon",
+	"zeta":    "\\zeta",
+	"eta":     "\\eta",
+	"theta":   "\\theta",
+	"iota":    "\\iota",
+	"kappa":   "\\kappa",
+	"lambda":  "\\lambda",
+	"mu":      "\\mu",
+	"nu":      "\\nu",
+	"xi":      "\\xi",
+	"omicron": "o",
+	"pi":      "\\pi",
+	"rho":     "\\rho",
+	"sigma":   "\\sigma",
+	"tau":     "\\tau",
+	"upsilon": "\\upsilon",
+	"phi":     "\\phi",
+	"chi":     "\\chi",
+	"psi":     "\\psi",
+	"omega":   "\\omega",
+	"Gamma":   "\\Gamma",
+	"Delta":   "\\Delta",
+	"Theta":   "\\Theta",
+	"Lambda":  "\\Lambda",
+	"Xi":      "\\Xi",
+	"Pi":      "\\Pi",
+	"Sigma":   "\\Sigma",
+	"Upsilon": "\\Upsilon",
+	"Phi":     "\\Phi",
+	"Psi":     "\\Psi",
+	"Omega":   "\\Omega",
+}
+
+// ToLaTex 生成节点latex
+func ToLaTex(expr ExprNode, ctx context.Context) string {
+	return toLaTex(ctx, EnvironmentFrom(ctx), expr)
+}
+
+// ToLaTex 在当前环境中生成节点latex
+func (e *Environment) ToLaTex(expr ExprNode, ctx context.Context) string {
+	return ToLaTex(expr, WithEnvironment(ctx, e))
+}
+
+func toLaTex(ctx context.Context, env *Environment, expr ExprNode) string {
+	switch node := expr.(type) {
+
+	case OperatorExprNode:
+		if isUnaryMinus(node) {
+			r := toLaTex(ctx, env, node.Rhs)
+			if _, ok := node.Rhs.(OperatorExprNode); ok {
+				r = latexParens(r)
+			}
+			return "-" + r
+		}
+		l := toLaTex(ctx, env, node.Lhs)
+		r := toLaTex(ctx, env, node.Rhs)
+		operator, ok := env.Operator(node.Op)
+		if !ok {
+			return l + " " + node.Op + " " + r
+		}
+		if latexNeedParens(env, node.Op, node.Lhs, false) {
+			l = latexParens(l)
+		}
+		if latexNeedParens(env, node.Op, node.Rhs, true) {
+			r = latexParens(r)
+		}
+		return operator.ToLaTex(l, r)
+
+	case UnaryExprNode:
+		operand := toLaTex(ctx, env, node.Operand)
+		if latexNeedParens(env, node.Op, node.Operand, true) {
+			operand = latexParens(operand)
+		}
+		operator, _ := env.Operator(node.Op)
+		if u, ok := operator.(UnaryOperatorItem); ok {
+			return u.ToUnaryLaTex(operand)
+		}
+		return node.Op + operand
+
+	case ConditionalExprNode:
+		return "\\begin{cases} " + toLaTex(ctx, env, node.Then) +
+			" & \\text{if } " + toLaTex(ctx, env, node.Cond) +
+			" \\\\ " + toLaTex(ctx, env, node.Else) +
+			" & \\text{otherwise} \\end{cases}"
+
+	case NumberExprNode:
+		return latexNumber(node)
+
+	case ConstExprNode:
+		if s, ok := env.ConstLaTex(node.Name); ok {
+			return s
+		}
+		return latexName(node.Name)
+
+	case VariableExprNode:
+		return latexName(node.Val)
+
+	case StringExprNode:
+		return "\\text{``" + latexTextEscape(node.Val) + "''}"
+
+	case FunCallerExprNode:
+		if def, ok := env.Func(node.Name); ok {
+			if f, ok := def.(LaTexFunc); ok {
+				return f.LaTex(ctx, node.Arg...)
+			}
+		}
+		args := make([]string, len(node.Arg))
+		for i, arg := range node.Arg {
+			args[i] = toLaTex(ctx, env, arg)
+		}
+		return "\\operatorname{" + latexEscape(node.Name) + "}\\left(" + strings.Join(args, ", ") + "\\right)"
+	}
+
+	return ""
+}
+
+// isUnaryMinus 判断是否为解析一元负号生成的 0 - x 节点
+func isUnaryMinus(node OperatorExprNode) bool {
+	return node.Op == "-" && isPrefixOperand(node.Lhs)
+}
+
+// isPrefixOperand 判断是否为解析前缀操作符时生成的左操作数占位节点
+func isPrefixOperand(expr ExprNode) bool {
+	n, ok := expr.(NumberExprNode)
+	return ok && n.Str == "" && n.Val == 0
+}
+
+// latexNeedParens 判断子节点是否需要括号
+func latexNeedParens(env *Environment, op string, child ExprNode, right bool) bool {
+	switch op {
+	case "/":
+		// \frac自带分组
+		return false
+	case "!":
+		_, ok := child.(OperatorExprNode)
+		return ok
+	case "^":
+		if right {
+			return false
+		}
+		switch c := child.(type) {
+		case OperatorExprNode, UnaryExprNode, ConditionalExprNode:
+			return true
+		case NumberExprNode:
+			return c.Val < 0
+		}
+		return false
+	}
+
+	switch c := child.(type) {
+	case OperatorExprNode:
+		if isPrefixOperand(c.Lhs) {
+			return right
+		}
+		p, ok := env.Operator(op)
+		if !ok {
+			return true
+		}
+		cp, ok := env.Operator(c.Op)
+		if !ok {
+			return true
+		}
+		if cp.Precedence() != p.Precedence() {
+			return cp.Precedence() < p.Precedence()
+		}
+		return right && op != "+" && op != "*"
+	case UnaryExprNode:
+		return right
+	case ConditionalExprNode:
+		return true
+	case NumberExprNode:
+		return right && c.Val < 0
+	}
+	return false
+}
+
+func latexParens(s string) string {
+	return "\\left(" + s + "\\right)"
+}
+
+// latexNumber 科学计数法转换为 a \times 10^{b}
+func latexNumber(n NumberExprNode) string {
+	s := n.Str
+	if s == "" {
+		s = Float64ToStr(n.Val)
+	}
+	if i := strings.IndexAny(s, "eE"); i > 0 {
+		exp := strings.TrimPrefix(s[i+1:], "+")
+		return s[:i] + " \\times 10^{" + exp + "}"
+	}
+	return s
+}
+
+// latexName 变量名转换，支持希腊字母及下标，如 alpha_1 => \alpha_{1}，x2 => x_{2}
+func latexName(name string) string {
+	base, sub := name, ""
+	if i := strings.IndexByte(name, '_'); i > 0 && i < len(name)-1 {
+		base, sub = name[:i], name[i+1:]
+	} else {
+		i := len(name)
+		for i > 0 && '0' <= name[i-1] && name[i-1] <= '9' {
+			i--
+		}
+		if i > 0 && i < len(name) {
+			base, sub = name[:i], name[i:]
+		}
+	}
+	s := latexSymbol(base)
+	if sub != "" {
+		s += "_{" + latexSymbol(sub) + "}"
+	}
+	return s
+}
+
+func latexSymbol(s string) string {
+	if g, ok := greekLetters[s]; ok {
+		return g
+	}
+	primes := ""
+	for strings.HasSuffix(s, "'") {
+		s = s[:len(s)-1]
+		primes += "'"
+	}
+	if len(s) <= 1 || isDigits(s) {
+		return latexEscape(s) + primes
+	}
+	return "\\mathrm{" + latexEscape(s) + "}" + primes
+}
+
+func latexEscape(s string) string {
+	return strings.NewReplacer("$", "\\$", "#", "\\#", "_", "\\_").Replace(s)
+}
+
+// latexTextEscape 转义\text{}中的特殊字符
+func latexTextEscape(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\textbackslash{}", "{", "\\{", "}", "\\}", "%", "\\%", "&", "\\&",
+		"#", "\\#", "$", "\\$", "_", "\\_", "~", "\\textasciitilde{}", "^", "\\textasciicircum{}",
+	).Replace(s)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
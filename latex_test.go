@@ -0,0 +1,45 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestToLaTex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`"50% a_b {x} & #1 $2 \\"`, "\\text{``50\\% a\\_b \\{x\\} \\& \\#1 \\$2 \\textbackslash{}''}"},
+		{`"~^"`, "\\text{``\\textasciitilde{}\\textasciicircum{}''}"},
+	}
+	for _, tt := range tests {
+		if got := ToLaTex(mustParse(t, tt.src), context.Background()); got != tt.want {
+			t.Errorf("ToLaTex(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestToLaTexNodes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a/b", "\\frac{a}{b}"},
+		{"x^2", "x^{2}"},
+		{"sqrt(x)", "\\sqrt{x}"},
+		{"abs(x)", "\\left|x\\right|"},
+		{"alpha_1 * pi", "\\alpha_{1} \\times π"},
+		{"x2", "x_{2}"},
+		{"(a+b)*c", "\\left(a + b\\right) \\times c"},
+		{"(a+b)^2", "\\left(a + b\\right)^{2}"},
+		{"pow(a+b, 2)", "{\\left(a + b\\right)}^{2}"},
+		{"pow(x, 2)", "{x}^{2}"},
+		{"a > b ? 1 : 2", "\\begin{cases} 1 & \\text{if } a > b \\\\ 2 & \\text{otherwise} \\end{cases}"},
+	}
+	for _, tt := range tests {
+		if got := ToLaTex(mustParse(t, tt.src), context.Background()); got != tt.want {
+			t.Errorf("ToLaTex(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package mathastc
+
+import "context"
+
+// Limits 计算限制，0表示不限制
+type Limits struct {
+	MaxNodes    int // 最多访问的节点数
+	MaxDepth    int // 最大递归深度，按语法树深度计算，左结合的连续运算如 a+b+c 每个操作符计为一层
+	MaxVarDepth int // 字符串、表达式变量的最大展开深度
+}
+
+// DefaultLimits 默认计算限制，防止异常公式导致栈溢出
+// 默认递归深度支持约10万项的连续求和，更长的公式需通过WithLimits放宽限制
+var DefaultLimits = Limits{
+	MaxDepth:    100000,
+	MaxVarDepth: 100,
+}
+
+type limitsCtxKey struct{}
+
+// WithLimits 设置计算限制
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsCtxKey{}, limits)
+}
+
+// LimitsFrom 获取上下文中的计算限制，未设置时返回DefaultLimits
+func LimitsFrom(ctx context.Context) Limits {
+	if ctx != nil {
+		if l, ok := ctx.Value(limitsCtxKey{}).(Limits); ok {
+			return l
+		}
+	}
+	return DefaultLimits
+}
@@ -0,0 +1,46 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsFlatSum(t *testing.T) {
+	expr := mustParse(t, strings.Repeat("1+", 10000)+"1")
+	v, err := Evaluate(context.Background(), expr)
+	if err != nil || v != 10001 {
+		t.Errorf("10001-term sum = %v, %v", v, err)
+	}
+}
+
+func TestLimitsExceeded(t *testing.T) {
+	var limitErr *LimitError
+	ctx := WithLimits(context.Background(), Limits{MaxDepth: 10})
+	_, err := Evaluate(ctx, mustParse(t, strings.Repeat("1+", 20)+"1"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != 10 {
+		t.Errorf("max depth: %v", err)
+	}
+
+	ctx = WithLimits(context.Background(), Limits{MaxNodes: 5})
+	_, err = Evaluate(ctx, mustParse(t, "1+2+3+4"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != 5 {
+		t.Errorf("max nodes: %v", err)
+	}
+
+	ctx = WithParameter(WithLimits(context.Background(), Limits{MaxVarDepth: 2}),
+		NewParameter(map[string]any{"a": "b+1", "b": "c+1", "c": "1"}, nil))
+	_, err = Evaluate(ctx, mustParse(t, "a"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != 2 {
+		t.Errorf("max variable depth: %v", err)
+	}
+}
+
+func TestLimitsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Evaluate(ctx, mustParse(t, "1+1")); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: %v", err)
+	}
+}
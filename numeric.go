@@ -0,0 +1,283 @@
+package mathastc
+
+import (
+	"errors"
+)
+
+// arith 数值类型T的运算，高精度等计算模式通过实现arith复用节点遍历、变量展开及计算限制
+// 返回ok为false的运算以float64计算后再转换为T
+type arith[T any] interface {
+	// literal 数值字面量
+	literal(n NumberExprNode) (T, error)
+	// convert 转换变量值，优先于字符串及表达式变量的展开，不支持的类型返回false
+	convert(value any) (T, bool)
+	fromFloat(f float64) (T, error)
+	toFloat(v T) float64
+	// truth 条件运算的真值
+	truth(v T) (bool, error)
+	operator(operator OperatorItem, op string, a T, b T) (T, bool, error)
+	unary(operator OperatorItem, op string, a T) (T, bool, error)
+	call(def DefFunc, name string, args []T) (T, bool, error)
+}
+
+// shortCircuitArith 自定义短路求值，未实现时以float64调用ShortCircuitOperator
+type shortCircuitArith[T any] interface {
+	shortCircuit(operator OperatorItem, op string, a T) (T, bool, error)
+}
+
+// textArith 支持字符串字面量
+type textArith[T any] interface {
+	text(s string) T
+}
+
+// unboundArith 变量未绑定值时提供默认值，如复数计算的虚数单位
+type unboundArith[T any] interface {
+	unbound(name string) (T, bool)
+}
+
+// joinArith 条件的真值不确定时计算两个分支并合并结果，如区间计算
+type joinArith[T any] interface {
+	uncertain(c T) bool
+	join(a T, b T) T
+}
+
+// constArith 以计算精度提供常量值，未实现时常量以float64值参与计算
+type constArith[T any] interface {
+	constant(val float64) (T, error)
+}
+
+// numEvaluator 以数值类型T计算表达式，共享evaluator的计算状态
+type numEvaluator[T any] struct {
+	*evaluator
+	ar     arith[T]
+	values map[string]T // Options.MemoVars开启时已计算的变量值
+}
+
+func newNumEvaluator[T any](ev *evaluator, ar arith[T]) *numEvaluator[T] {
+	return &numEvaluator[T]{evaluator: ev, ar: ar}
+}
+
+func (ev *numEvaluator[T]) eval(expr ExprNode) (T, error) {
+	var zero T
+	if err := ev.enter(); err != nil {
+		return zero, err
+	}
+	defer func() {
+		ev.st.depth--
+	}()
+
+	switch node := expr.(type) {
+
+	case OperatorExprNode:
+		l, err := ev.eval(node.Lhs)
+		if err != nil {
+			return zero, err
+		}
+		operator, _ := ev.env.Operator(node.Op)
+		if sc, ok := ev.ar.(shortCircuitArith[T]); ok {
+			v, ok, err := sc.shortCircuit(operator, node.Op, l)
+			if err != nil {
+				return zero, &EvalError{Expr: node, Err: err}
+			}
+			if ok {
+				return v, nil
+			}
+		} else if s, ok := operator.(ShortCircuitOperator); ok {
+			if v, ok := s.ShortCircuit(ev.ar.toFloat(l)); ok {
+				return ev.fromFloat(node, v)
+			}
+		}
+		r, err := ev.eval(node.Rhs)
+		if err != nil {
+			return zero, err
+		}
+		v, ok, err := ev.ar.operator(operator, node.Op, l, r)
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		if ok {
+			return v, nil
+		}
+		f, err := evalOperator(operator, node.Op, ev.ar.toFloat(l), ev.ar.toFloat(r))
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		return ev.fromFloat(node, f)
+
+	case UnaryExprNode:
+		a, err := ev.eval(node.Operand)
+		if err != nil {
+			return zero, err
+		}
+		operator, _ := ev.env.Operator(node.Op)
+		v, ok, err := ev.ar.unary(operator, node.Op, a)
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		if ok {
+			return v, nil
+		}
+		f, err := evalUnary(operator, node.Op, ev.ar.toFloat(a))
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		return ev.fromFloat(node, f)
+
+	case ConditionalExprNode:
+		c, err := ev.eval(node.Cond)
+		if err != nil {
+			return zero, err
+		}
+		if j, ok := ev.ar.(joinArith[T]); ok && j.uncertain(c) {
+			t, err := ev.eval(node.Then)
+			if err != nil {
+				return zero, err
+			}
+			e, err := ev.eval(node.Else)
+			if err != nil {
+				return zero, err
+			}
+			return j.join(t, e), nil
+		}
+		b, err := ev.ar.truth(c)
+		if err != nil {
+			return zero, &EvalError{Expr: node.Cond, Err: err}
+		}
+		if b {
+			return ev.eval(node.Then)
+		}
+		return ev.eval(node.Else)
+
+	case NumberExprNode:
+		v, err := ev.ar.literal(node)
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		return v, nil
+
+	case ConstExprNode:
+		if c, ok := ev.ar.(constArith[T]); ok {
+			v, err := c.constant(node.Val)
+			if err != nil {
+				return zero, &EvalError{Expr: node, Err: err}
+			}
+			return v, nil
+		}
+		return ev.fromFloat(node, node.Val)
+
+	case StringExprNode:
+		if t, ok := ev.ar.(textArith[T]); ok {
+			return t.text(node.Val), nil
+		}
+		return zero, &EvalError{Expr: node, Err: &TypeError{Want: "number", Kinds: []ValueKind{StringKind}}}
+
+	case VariableExprNode:
+		v, err := ev.evalVariable(node.Val)
+		if err != nil {
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		return v, nil
+
+	case FunCallerExprNode:
+		def, _ := ev.env.Func(node.Name)
+		v, err := ev.evalCall(def, node)
+		if err != nil {
+			var evalErr *EvalError
+			if errors.As(err, &evalErr) {
+				return zero, err
+			}
+			return zero, &EvalError{Expr: node, Err: err}
+		}
+		return v, nil
+	}
+
+	return ev.ar.fromFloat(0)
+}
+
+func (ev *numEvaluator[T]) fromFloat(node ExprNode, f float64) (T, error) {
+	v, err := ev.ar.fromFloat(f)
+	if err != nil {
+		return v, &EvalError{Expr: node, Err: err}
+	}
+	return v, nil
+}
+
+// evalCall 调用函数，函数未提供T的实现时参数转换为NumberExprNode以float64计算
+func (ev *numEvaluator[T]) evalCall(def DefFunc, node FunCallerExprNode) (T, error) {
+	var zero T
+	if def == nil {
+		return zero, &FuncError{Name: node.Name, Err: ErrUndefinedFunc}
+	}
+	args := make([]T, len(node.Arg))
+	for i, arg := range node.Arg {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return zero, err
+		}
+		args[i] = v
+	}
+	v, ok, err := ev.ar.call(def, node.Name, args)
+	if err != nil {
+		return zero, &FuncError{Name: node.Name, Err: err}
+	}
+	if ok {
+		return v, nil
+	}
+	nodes := make([]ExprNode, len(args))
+	for i, a := range args {
+		nodes[i] = newNumber(ev.ar.toFloat(a))
+	}
+	f, err := evalFunc(ev.ctx, def, node.Name, nodes)
+	if err != nil {
+		return zero, err
+	}
+	return ev.ar.fromFloat(f)
+}
+
+// evalVariable 计算变量值，变量展开规则与float64计算一致
+func (ev *numEvaluator[T]) evalVariable(name string) (T, error) {
+	var zero T
+	value, err := ev.resolve(name)
+	if err != nil {
+		var unbound *UnboundVariableError
+		if u, ok := ev.ar.(unboundArith[T]); ok && (errors.As(err, &unbound) || errors.Is(err, ErrNoParameter)) {
+			if v, ok := u.unbound(name); ok {
+				return v, nil
+			}
+		}
+		return zero, err
+	}
+	if v, ok := ev.ar.convert(value); ok {
+		return v, nil
+	}
+	expression, err := ev.variableExpr(name, value)
+	if err != nil {
+		return zero, err
+	}
+	if expression == nil {
+		if f, ok := toFloat64(value); ok {
+			return ev.ar.fromFloat(f)
+		}
+		return zero, &VariableError{Name: name, Value: value, Err: ErrUnknownValueType}
+	}
+
+	if v, ok := ev.values[name]; ok {
+		return v, nil
+	}
+	if err := ev.pushVar(name); err != nil {
+		return zero, err
+	}
+	defer ev.popVar()
+
+	v, err := ev.eval(expression)
+	if err != nil {
+		return zero, variableError(name, value, expression, err)
+	}
+	if ev.st.opts.MemoVars {
+		if ev.values == nil {
+			ev.values = map[string]T{}
+		}
+		ev.values[name] = v
+	}
+	return v, nil
+}
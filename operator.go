@@ -1,7 +1,6 @@
 package mathastc
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"math/big"
@@ -10,6 +9,12 @@ import (
 const (
 	NonePrecedence = -1 // 权重值
 	NoneResult     = 0.0
+	TrueResult     = 1.0 // 逻辑真
+	FalseResult    = 0.0 // 逻辑假
+
+	UnaryPrecedence = 50 // 一元操作符权重，低于^高于*/，-2^2 => -(2^2)
+
+	maxSymbolLen = 3 // 多字符操作符最大长度
 )
 
 type OperatorItem interface {
@@ -20,17 +25,100 @@ type OperatorItem interface {
 	ToExprStr(a string, b string) string
 }
 
-var Operators = map[byte]OperatorItem{
-	'(': &LBrackets{},
-	')': &RBrackets{},
-	//'[': &LMBrackets{},
-	//']': &RMBrackets{},
-	'+': &Plus{},
-	'-': &Minus{},
-	'*': &Mul{},
-	'/': &Div{},
-	'^': &Pow{},
-	'%': &Mod{},
+// OperatorEvaluator 以error代替panic返回运算异常，Evaluate优先使用
+type OperatorEvaluator interface {
+	Evaluate(a float64, b float64) (float64, error)
+}
+
+// Associativity 结合性
+type Associativity int
+
+const (
+	LeftAssoc  Associativity = iota // 左结合
+	RightAssoc                      // 右结合
+)
+
+// AssociativeOperator 声明结合性的操作符，未实现时视为左结合
+type AssociativeOperator interface {
+	Associativity() Associativity
+}
+
+// UnaryOperatorItem 前缀一元操作符，如 -x、+x、!x
+type UnaryOperatorItem interface {
+	UnaryResult(a float64) float64
+	ToUnaryExprStr(a string) string
+	ToUnaryLaTex(a string) string
+}
+
+// SymbolOperator 多字符操作符，如 <=、&&，按Symbol注册及匹配，Name返回首字符
+type SymbolOperator interface {
+	OperatorItem
+	Symbol() string
+}
+
+// ShortCircuitOperator 短路求值，左操作数可确定结果时不再计算右操作数
+type ShortCircuitOperator interface {
+	ShortCircuit(a float64) (float64, bool)
+}
+
+// Operators 默认运行环境的操作符
+var Operators = builtinOperators()
+
+// SymbolOperators 默认运行环境的多字符操作符
+var SymbolOperators = builtinSymbolOperators()
+
+// 内置操作符
+func builtinOperators() map[byte]OperatorItem {
+	return map[byte]OperatorItem{
+		'(': &LBrackets{},
+		')': &RBrackets{},
+		//'[': &LMBrackets{},
+		//']': &RMBrackets{},
+		'+': &Plus{},
+		'-': &Minus{},
+		'*': &Mul{},
+		'/': &Div{},
+		'^': &Pow{},
+		'%': &Mod{},
+		'<': &Less{},
+		'>': &Greater{},
+		'!': &Not{},
+	}
+}
+
+// 内置多字符操作符
+func builtinSymbolOperators() map[string]OperatorItem {
+	return map[string]OperatorItem{
+		"<=": &LessEqual{},
+		">=": &GreaterEqual{},
+		"==": &Equal{},
+		"!=": &NotEqual{},
+		"&&": &And{},
+		"||": &Or{},
+	}
+}
+
+// operatorSymbol 获取操作符的符号
+func operatorSymbol(item OperatorItem) string {
+	if s, ok := item.(SymbolOperator); ok {
+		return s.Symbol()
+	}
+	return string(item.Name())
+}
+
+// operatorAssoc 获取操作符结合性
+func operatorAssoc(item OperatorItem) Associativity {
+	if a, ok := item.(AssociativeOperator); ok {
+		return a.Associativity()
+	}
+	return LeftAssoc
+}
+
+func boolResult(b bool) float64 {
+	if b {
+		return TrueResult
+	}
+	return FalseResult
 }
 
 // LBrackets 左括号
@@ -112,16 +200,43 @@ func (d *Div) Precedence() int {
 }
 
 func (d *Div) Result(a float64, b float64) float64 {
-	if b == 0 {
-		panic(errors.New(
-			fmt.Sprintf("violation of arithmetic specification: a division by zero in ExprASTResult: [%g/%g]",
-				a,
-				b)))
+	f, err := d.Evaluate(a, b)
+	if err != nil {
+		panic(err)
 	}
-	f, _ := new(big.Float).Quo(new(big.Float).SetFloat64(a), new(big.Float).SetFloat64(b)).Float64()
 	return f
 }
 
+func (d *Div) Evaluate(a float64, b float64) (f float64, err error) {
+	if b == 0 {
+		return NoneResult, &OperatorError{Op: "/", Lhs: a, Rhs: b, Err: ErrDivisionByZero}
+	}
+	// NaN操作数及 Inf/Inf 时big.Float以ErrNaN panic
+	defer func() {
+		if e := recover(); e != nil {
+			f, err = NoneResult, &OperatorError{Op: "/", Lhs: a, Rhs: b, Err: recoverError(e)}
+		}
+	}()
+	f, _ = new(big.Float).Quo(new(big.Float).SetFloat64(a), new(big.Float).SetFloat64(b)).Float64()
+	return f, nil
+}
+
+func (d *Div) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	return a.Quo(b, policy.scale(), policy.Rounding)
+}
+
+func (d *Div) ComplexResult(a complex128, b complex128) (complex128, error) {
+	if b == 0 {
+		return 0, ErrDivisionByZero
+	}
+	return a / b, nil
+}
+
+// IntervalResult 除数区间包含0时结果可能为无界区间
+func (d *Div) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalDiv(a, b)
+}
+
 func (d *Div) ToExprStr(a string, b string) string {
 	return fmt.Sprintf("%s/%s", a, b)
 }
@@ -149,6 +264,30 @@ func (m *Minus) Result(a float64, b float64) float64 {
 	return f
 }
 
+func (m *Minus) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	return a.Sub(b), nil
+}
+
+func (m *Minus) ComplexResult(a complex128, b complex128) (complex128, error) {
+	return a - b, nil
+}
+
+func (m *Minus) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalSub(a, b), nil
+}
+
+func (m *Minus) UnaryResult(a float64) float64 {
+	return -a
+}
+
+func (m *Minus) ToUnaryExprStr(a string) string {
+	return "-" + a
+}
+
+func (m *Minus) ToUnaryLaTex(a string) string {
+	return "-" + a
+}
+
 func (m *Minus) ToExprStr(a string, b string) string {
 	return fmt.Sprintf("%s - %s", a, b)
 }
@@ -170,21 +309,36 @@ func (m *Mod) Precedence() int {
 }
 
 func (m *Mod) Result(a float64, b float64) float64 {
-	if b == 0 {
-		panic(errors.New(
-			fmt.Sprintf("violation of arithmetic specification: a division by zero in ExprASTResult: [%g%%%g]",
-				a,
-				b)))
+	f, err := m.Evaluate(a, b)
+	if err != nil {
+		panic(err)
 	}
-	return float64(int(a) % int(b))
+	return f
+}
+
+func (m *Mod) Evaluate(a float64, b float64) (float64, error) {
+	if int(b) == 0 {
+		return NoneResult, &OperatorError{Op: "%", Lhs: a, Rhs: b, Err: ErrDivisionByZero}
+	}
+	return float64(int(a) % int(b)), nil
+}
+
+// IntervalResult 截断为整数后取余，除数区间包含绝对值小于1的取值时忽略这些取值
+func (m *Mod) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalMod(a, b)
+}
+
+// DecimalResult 定点小数取余，不截断为整数
+func (m *Mod) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	return a.Mod(b)
 }
 
 func (m *Mod) ToExprStr(a string, b string) string {
-	return fmt.Sprintf("(%s %% %s)", a, b)
+	return fmt.Sprintf("%s %% %s", a, b)
 }
 
 func (m *Mod) ToLaTex(a string, b string) string {
-	return fmt.Sprintf("(%s %% %s)", a, b)
+	return fmt.Sprintf("%s \\bmod %s", a, b)
 }
 
 // Mul 两数相乘
@@ -204,6 +358,18 @@ func (m *Mul) Result(a float64, b float64) float64 {
 	return f
 }
 
+func (m *Mul) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	return a.Mul(b), nil
+}
+
+func (m *Mul) ComplexResult(a complex128, b complex128) (complex128, error) {
+	return a * b, nil
+}
+
+func (m *Mul) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalMul(a, b), nil
+}
+
 func (m *Mul) ToExprStr(a string, b string) string {
 	return fmt.Sprintf("%s * %s", a, b)
 }
@@ -231,6 +397,30 @@ func (p *Plus) Result(a float64, b float64) float64 {
 	return f
 }
 
+func (p *Plus) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	return a.Add(b), nil
+}
+
+func (p *Plus) ComplexResult(a complex128, b complex128) (complex128, error) {
+	return a + b, nil
+}
+
+func (p *Plus) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalAdd(a, b), nil
+}
+
+func (p *Plus) UnaryResult(a float64) float64 {
+	return a
+}
+
+func (p *Plus) ToUnaryExprStr(a string) string {
+	return "+" + a
+}
+
+func (p *Plus) ToUnaryLaTex(a string) string {
+	return "+" + a
+}
+
 func (p *Plus) ToExprStr(a string, b string) string {
 	return fmt.Sprintf("%s + %s", a, b)
 }
@@ -251,10 +441,36 @@ func (p *Pow) Precedence() int {
 	return 60
 }
 
+func (p *Pow) Associativity() Associativity {
+	return RightAssoc
+}
+
 func (p *Pow) Result(a float64, b float64) float64 {
 	return math.Pow(a, b)
 }
 
+// DecimalResult 整数指数精确计算，非整数指数以float64计算后按计算规则舍入
+func (p *Pow) DecimalResult(a Decimal, b Decimal, policy DecimalPolicy) (Decimal, error) {
+	if n := b.Round(0, big.ToZero); n.Cmp(b) == 0 && n.int().IsInt64() {
+		return a.Pow(n.int().Int64(), policy.scale(), policy.Rounding)
+	}
+	d, err := DecimalFromFloat(math.Pow(a.Float64(), b.Float64()))
+	if err != nil {
+		return d, err
+	}
+	return d.Round(minInt(d.Scale(), policy.scale()), policy.Rounding), nil
+}
+
+// ComplexResult 实数结果以math.Pow计算，保持与float64计算一致，其他以cmplx.Pow计算主值
+func (p *Pow) ComplexResult(a complex128, b complex128) (complex128, error) {
+	return complexPow(a, b), nil
+}
+
+// IntervalResult 整数指数按奇偶性计算，偶数次幂在包含0的区间上最小值为0
+func (p *Pow) IntervalResult(a Interval, b Interval) (Interval, error) {
+	return intervalPow(a, b)
+}
+
 func (p *Pow) ToExprStr(a string, b string) string {
 	return fmt.Sprintf("%s^%s", a, b)
 }
@@ -262,3 +478,263 @@ func (p *Pow) ToExprStr(a string, b string) string {
 func (p *Pow) ToLaTex(a string, b string) string {
 	return fmt.Sprintf("%s^{%s}", a, b)
 }
+
+// Less 小于
+type Less struct {
+}
+
+func (l *Less) Name() byte {
+	return '<'
+}
+
+func (l *Less) Precedence() int {
+	return 16
+}
+
+func (l *Less) Result(a float64, b float64) float64 {
+	return boolResult(a < b)
+}
+
+func (l *Less) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s < %s", a, b)
+}
+
+func (l *Less) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s < %s", a, b)
+}
+
+// LessEqual 小于等于
+type LessEqual struct {
+}
+
+func (l *LessEqual) Name() byte {
+	return '<'
+}
+
+func (l *LessEqual) Symbol() string {
+	return "<="
+}
+
+func (l *LessEqual) Precedence() int {
+	return 16
+}
+
+func (l *LessEqual) Result(a float64, b float64) float64 {
+	return boolResult(a <= b)
+}
+
+func (l *LessEqual) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s <= %s", a, b)
+}
+
+func (l *LessEqual) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s \\leq %s", a, b)
+}
+
+// Greater 大于
+type Greater struct {
+}
+
+func (g *Greater) Name() byte {
+	return '>'
+}
+
+func (g *Greater) Precedence() int {
+	return 16
+}
+
+func (g *Greater) Result(a float64, b float64) float64 {
+	return boolResult(a > b)
+}
+
+func (g *Greater) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s > %s", a, b)
+}
+
+func (g *Greater) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s > %s", a, b)
+}
+
+// GreaterEqual 大于等于
+type GreaterEqual struct {
+}
+
+func (g *GreaterEqual) Name() byte {
+	return '>'
+}
+
+func (g *GreaterEqual) Symbol() string {
+	return ">="
+}
+
+func (g *GreaterEqual) Precedence() int {
+	return 16
+}
+
+func (g *GreaterEqual) Result(a float64, b float64) float64 {
+	return boolResult(a >= b)
+}
+
+func (g *GreaterEqual) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s >= %s", a, b)
+}
+
+func (g *GreaterEqual) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s \\geq %s", a, b)
+}
+
+// Equal 等于
+type Equal struct {
+}
+
+func (e *Equal) Name() byte {
+	return '='
+}
+
+func (e *Equal) Symbol() string {
+	return "=="
+}
+
+func (e *Equal) Precedence() int {
+	return 14
+}
+
+func (e *Equal) Result(a float64, b float64) float64 {
+	return boolResult(a == b)
+}
+
+func (e *Equal) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s == %s", a, b)
+}
+
+func (e *Equal) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s = %s", a, b)
+}
+
+// NotEqual 不等于
+type NotEqual struct {
+}
+
+func (n *NotEqual) Name() byte {
+	return '!'
+}
+
+func (n *NotEqual) Symbol() string {
+	return "!="
+}
+
+func (n *NotEqual) Precedence() int {
+	return 14
+}
+
+func (n *NotEqual) Result(a float64, b float64) float64 {
+	return boolResult(a != b)
+}
+
+func (n *NotEqual) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s != %s", a, b)
+}
+
+func (n *NotEqual) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s \\neq %s", a, b)
+}
+
+// And 逻辑与，非0为真
+type And struct {
+}
+
+func (o *And) Name() byte {
+	return '&'
+}
+
+func (o *And) Symbol() string {
+	return "&&"
+}
+
+func (o *And) Precedence() int {
+	return 10
+}
+
+func (o *And) Result(a float64, b float64) float64 {
+	return boolResult(a != 0 && b != 0)
+}
+
+func (o *And) ShortCircuit(a float64) (float64, bool) {
+	return FalseResult, a == 0
+}
+
+func (o *And) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s && %s", a, b)
+}
+
+func (o *And) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s \\land %s", a, b)
+}
+
+// Or 逻辑或，非0为真
+type Or struct {
+}
+
+func (o *Or) Name() byte {
+	return '|'
+}
+
+func (o *Or) Symbol() string {
+	return "||"
+}
+
+func (o *Or) Precedence() int {
+	return 8
+}
+
+func (o *Or) Result(a float64, b float64) float64 {
+	return boolResult(a != 0 || b != 0)
+}
+
+func (o *Or) ShortCircuit(a float64) (float64, bool) {
+	return TrueResult, a != 0
+}
+
+func (o *Or) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("%s || %s", a, b)
+}
+
+func (o *Or) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("%s \\lor %s", a, b)
+}
+
+// Not 逻辑非，前缀操作符，仅作用于右操作数
+type Not struct {
+}
+
+func (n *Not) Name() byte {
+	return '!'
+}
+
+func (n *Not) Precedence() int {
+	return NonePrecedence
+}
+
+func (n *Not) Result(a float64, b float64) float64 {
+	return boolResult(b == 0)
+}
+
+func (n *Not) UnaryResult(a float64) float64 {
+	return boolResult(a == 0)
+}
+
+func (n *Not) ToUnaryExprStr(a string) string {
+	return "!" + a
+}
+
+func (n *Not) ToUnaryLaTex(a string) string {
+	return "\\lnot " + a
+}
+
+func (n *Not) ToExprStr(a string, b string) string {
+	return fmt.Sprintf("!%s", b)
+}
+
+func (n *Not) ToLaTex(a string, b string) string {
+	return fmt.Sprintf("\\lnot %s", b)
+}
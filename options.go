@@ -0,0 +1,88 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"math/big"
+)
+
+// AngleUnit 三角函数的角度单位
+type AngleUnit int
+
+const (
+	Radian AngleUnit = iota // 弧度
+	Degree                  // 角度
+)
+
+// ToRadian 转换为弧度
+func (u AngleUnit) ToRadian(x float64) float64 {
+	if u == Degree {
+		return x * math.Pi / 180
+	}
+	return x
+}
+
+// FromRadian 由弧度转换
+func (u AngleUnit) FromRadian(x float64) float64 {
+	if u == Degree {
+		return x * 180 / math.Pi
+	}
+	return x
+}
+
+// PrecisionMode 数值精度模式
+type PrecisionMode int
+
+const (
+	PrecisionFloat64  PrecisionMode = iota // float64计算
+	PrecisionBigFloat                      // big.Float计算，精度及舍入方式由Options.Prec、Options.Rounding指定
+	PrecisionRat                           // big.Rat精确计算，字面量按源码文本精确解析
+	PrecisionDecimal                       // 定点小数计算，计算规则由Options.Decimal或Parameter.Decimal指定
+)
+
+// DefaultPrec PrecisionBigFloat默认的二进制精度
+const DefaultPrec uint = 256
+
+// VariableResolver 变量解析，变量不存在时返回UnboundVariableError，Parameter为默认实现
+// 返回值为any而非Value：除Value、Go数值类型、bool外，还可以是表达式字符串、ExprNode，
+// 以及各精度模式的数值如*big.Rat、*big.Float、Decimal、complex128、Interval
+type VariableResolver interface {
+	Resolve(ctx context.Context, name string) (any, error)
+}
+
+// Options 计算选项，通过WithOptions设置，DefFunc可通过OptionsFrom读取
+type Options struct {
+	AngleUnit AngleUnit        // 三角函数角度单位
+	Precision PrecisionMode    // 数值精度模式
+	Prec      uint             // PrecisionBigFloat的二进制精度，为0时使用DefaultPrec
+	Rounding  big.RoundingMode // PrecisionBigFloat的舍入方式
+	Decimal   DecimalPolicy    // PrecisionDecimal的小数位数及舍入方式
+	ImagUnit  string           // EvaluateComplex中表示虚数单位的未绑定变量名称，为空时使用i和j
+	Strict    bool             // 严格模式，运算结果为NaN或Inf时返回ErrNotFinite
+	Resolver  VariableResolver // 变量解析，为空时使用上下文中的Parameter
+	MemoVars  bool             // 单次计算中字符串及表达式变量的值只计算一次，变量值须与计算过程无关
+}
+
+type paramCtxKey struct{}
+
+type optionsCtxKey struct{}
+
+// WithParameter 设置计算参数
+func WithParameter(ctx context.Context, parameter *Parameter) context.Context {
+	return context.WithValue(ctx, paramCtxKey{}, parameter)
+}
+
+// WithOptions 设置计算选项
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsCtxKey{}, opts)
+}
+
+// OptionsFrom 获取上下文中的计算选项，未设置时返回零值
+func OptionsFrom(ctx context.Context) Options {
+	if ctx != nil {
+		if o, ok := ctx.Value(optionsCtxKey{}).(Options); ok {
+			return o
+		}
+	}
+	return Options{}
+}
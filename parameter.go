@@ -1,8 +1,30 @@
 package mathastc
 
+import "sync"
+
 type Parameter struct {
-	Vars map[string]any // number | string
-	Diff []string
+	Vars    map[string]any // number | string
+	Diff    []string
+	Decimal *DecimalPolicy // 定点小数计算规则，设置时优先于Options.Decimal
+
+	cache *exprCache // 首次解析字符串变量时创建
+}
+
+// cacheMu 保护Parameter.cache的延迟创建，Parameter可按值复制，不能内含锁
+var cacheMu sync.RWMutex
+
+// exprCache 字符串变量的解析缓存，变量值或运行环境的定义变更后重新解析
+type exprCache struct {
+	mu      sync.RWMutex
+	entries map[string]exprCacheEntry
+}
+
+type exprCacheEntry struct {
+	src  string
+	env  *Environment
+	rev  uint64
+	expr ExprNode
+	err  error
 }
 
 func (p Parameter) HasDiffVar(v string) bool {
@@ -23,3 +45,39 @@ func NewParameter(vars map[string]any, diff []string) *Parameter {
 	}
 	return &Parameter{Vars: vars, Diff: diff}
 }
+
+// parseCache 获取解析缓存，不存在时创建，直接构造的Parameter同样缓存解析结果
+func (p *Parameter) parseCache() *exprCache {
+	cacheMu.RLock()
+	c := p.cache
+	cacheMu.RUnlock()
+	if c != nil {
+		return c
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if p.cache == nil {
+		p.cache = &exprCache{entries: map[string]exprCacheEntry{}}
+	}
+	return p.cache
+}
+
+// parseVar 解析字符串变量并缓存解析结果
+func (p *Parameter) parseVar(env *Environment, name string, src string) (ExprNode, error) {
+	if p == nil {
+		return env.ParseExpression(src)
+	}
+	c := p.parseCache()
+	rev := env.revision()
+	c.mu.RLock()
+	e, ok := c.entries[name]
+	c.mu.RUnlock()
+	if ok && e.src == src && e.env == env && e.rev == rev {
+		return e.expr, e.err
+	}
+	expr, err := env.ParseExpression(src)
+	c.mu.Lock()
+	c.entries[name] = exprCacheEntry{src: src, env: env, rev: rev, expr: expr, err: err}
+	c.mu.Unlock()
+	return expr, err
+}
@@ -0,0 +1,91 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+// countFunc 记录调用次数的无参函数
+type countFunc struct {
+	n int
+}
+
+func (f *countFunc) Calculate(ctx context.Context, args ...ExprNode) float64 {
+	f.n++
+	return float64(f.n)
+}
+
+func (f *countFunc) ToExprStr(ctx context.Context, args ...ExprNode) string {
+	return "count()"
+}
+
+func (f *countFunc) Argc() int {
+	return 0
+}
+
+func TestParameterParseCache(t *testing.T) {
+	p := NewParameter(map[string]any{"a": "b * 2"}, nil)
+	env := DefaultEnvironment()
+	first, err := p.parseVar(env, "a", "b * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := p.parseVar(env, "a", "b * 2")
+	if !EqualExpr(first, second) || len(p.cache.entries) != 1 {
+		t.Errorf("cache entries = %d", len(p.cache.entries))
+	}
+
+	// 变量值变更后重新解析
+	changed, _ := p.parseVar(env, "a", "b * 3")
+	if Format(changed) != "b * 3" {
+		t.Errorf("changed variable = %s", Format(changed))
+	}
+
+	// 运行环境定义变更后重新解析，b由变量变为常量
+	local := NewEnvironment(nil)
+	before, _ := p.parseVar(local, "a", "b * 3")
+	if err := local.RegConst("b", 4); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := p.parseVar(local, "a", "b * 3")
+	if _, ok := before.(OperatorExprNode).Lhs.(VariableExprNode); !ok {
+		t.Errorf("before RegConst: %T", before.(OperatorExprNode).Lhs)
+	}
+	if _, ok := after.(OperatorExprNode).Lhs.(ConstExprNode); !ok {
+		t.Errorf("after RegConst: %T", after.(OperatorExprNode).Lhs)
+	}
+}
+
+func TestParameterLiteralCache(t *testing.T) {
+	p := &Parameter{Vars: map[string]any{"a": "b * 2"}}
+	env := DefaultEnvironment()
+	first, err := p.parseVar(env, "a", "b * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := p.parseVar(env, "a", "b * 2")
+	if p.cache == nil || len(p.cache.entries) != 1 || !EqualExpr(first, second) {
+		t.Error("Parameter built without NewParameter does not cache parsed variables")
+	}
+}
+
+func TestMemoVars(t *testing.T) {
+	env := NewEnvironment(nil)
+	f := &countFunc{}
+	if err := env.RegDefFunc("count", f); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.ParseExpression("a + a + a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithEnvironment(varsCtx(map[string]any{"a": "count()"}), env)
+
+	if v, err := Evaluate(ctx, expr); err != nil || v != 6 {
+		t.Errorf("without MemoVars = %v, %v", v, err)
+	}
+	f.n = 0
+	if v, err := Evaluate(WithOptions(ctx, Options{MemoVars: true}), expr); err != nil || v != 3 {
+		t.Errorf("with MemoVars = %v, %v", v, err)
+	}
+}
@@ -3,27 +3,20 @@ package mathastc
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 type Parser struct {
 	Source string
+	env    *Environment
 	ch     byte
 	offset int
 	err    error
 }
 
 func Parse(s string) ([]*Token, error) {
-	p := &Parser{
-		Source: s,
-		err:    nil,
-		ch:     s[0],
-	}
-	toks := p.parse()
-	if p.err != nil {
-		return nil, p.err
-	}
-	return toks, nil
+	return defaultEnv.Parse(s)
 }
 
 func (p *Parser) parse() []*Token {
@@ -49,14 +42,16 @@ func (p *Parser) nextTok() *Token {
 	start := p.offset
 	var tok *Token
 
-	// 判断是否操作符号, []()+-*/%^
-	if ounit, ok := Operators[p.ch]; ok == true {
+	// 判断是否操作符号, []()+-*/%^ 及 <= && 等多字符操作符，按最长匹配
+	if _, symbol, ok := p.env.matchOperator(p.Source[start:]); ok == true {
 		tok = &Token{
-			Value: string(ounit.Name()),
+			Value: symbol,
 			Type:  OperatorType,
 		}
 		tok.Offset = start
-		err = p.nextCh()
+		for i := 0; i < len(symbol); i++ {
+			err = p.nextCh()
+		}
 		return tok
 	}
 
@@ -70,6 +65,22 @@ func (p *Parser) nextTok() *Token {
 		return tok
 	}
 
+	// 判断是否字符串
+	if p.ch == '"' {
+		return p.stringTok()
+	}
+
+	// 判断是否条件运算符
+	if p.ch == '?' || p.ch == ':' {
+		tok = &Token{
+			Value: string(p.ch),
+			Type:  ConditionalType,
+		}
+		tok.Offset = start
+		err = p.nextCh()
+		return tok
+	}
+
 	// 判断是否逗号
 	if p.ch == ',' {
 		tok = &Token{
@@ -92,7 +103,7 @@ func (p *Parser) nextTok() *Token {
 	//}
 
 	if p.isChar(p.ch) {
-		for p.isWordChar(p.ch) && p.nextCh() == nil {
+		for (p.isWordChar(p.ch) || p.isPathSep()) && p.nextCh() == nil {
 		}
 		tok = &Token{
 			Value: p.Source[start:p.offset],
@@ -110,6 +121,30 @@ func (p *Parser) nextTok() *Token {
 	return tok
 }
 
+// stringTok 读取双引号字符串，转义规则与Go字符串一致
+func (p *Parser) stringTok() *Token {
+	start := p.offset
+	for p.nextCh() == nil {
+		if p.ch == '\\' {
+			if p.nextCh() != nil {
+				break
+			}
+			continue
+		}
+		if p.ch == '"' {
+			_ = p.nextCh()
+			raw := p.Source[start:p.offset]
+			if _, err := strconv.Unquote(raw); err != nil {
+				p.err = errors.New(fmt.Sprintf("invalid string literal %s, pos [%v:]\n%s", raw, start, ErrPos(p.Source, start)))
+				return nil
+			}
+			return &Token{Value: raw, Type: StringType, Offset: start}
+		}
+	}
+	p.err = errors.New(fmt.Sprintf("unterminated string literal, pos [%v:]\n%s", start, ErrPos(p.Source, start)))
+	return nil
+}
+
 func (p *Parser) IsLiteral(v byte) bool {
 	switch v {
 	case
@@ -161,7 +196,12 @@ func (p *Parser) isChar(c byte) bool {
 }
 
 func (p *Parser) isWordChar(c byte) bool {
-	return p.isChar(c) || '0' <= c && c <= '9' || c == '$' || c == '#'
+	return p.isChar(c) || '0' <= c && c <= '9' || c == '$' || c == '#' || c == '_'
+}
+
+// isPathSep 变量路径分隔符，如 order.total、items.0.price
+func (p *Parser) isPathSep() bool {
+	return p.ch == '.' && p.offset+1 < len(p.Source) && p.isWordChar(p.Source[p.offset+1])
 }
 
 //func (p *Parser) isVar(v byte) bool {
@@ -0,0 +1,155 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseEmpty(t *testing.T) {
+	if _, err := Parse(""); !errors.Is(err, ErrEmptyExpression) {
+		t.Fatalf("Parse(\"\") err = %v, want ErrEmptyExpression", err)
+	}
+	if _, err := ParseExpression(""); !errors.Is(err, ErrEmptyExpression) {
+		t.Fatalf("ParseExpression(\"\") err = %v, want ErrEmptyExpression", err)
+	}
+	if err := NewSheet(nil).Set("a", ""); !errors.Is(err, ErrEmptyExpression) {
+		t.Fatalf("Sheet.Set(\"\") err = %v, want ErrEmptyExpression", err)
+	}
+}
+
+func TestParseOperators(t *testing.T) {
+	ctx := varsCtx(map[string]any{"a": 1, "b": 2})
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"a < b", 1},
+		{"a >= b", 0},
+		{"a + 1 == b", 1},
+		{"a != b && b > 1", 1},
+		{"a > b || b > 1", 1},
+		{"!(a > b)", 1},
+		{"a > b ? 10 : b > 1 ? 20 : 30", 20},
+		{"a < b ? a : b + 100", 1},
+		{"(a < b ? a : b) + 100", 101},
+		// 短路时不计算右侧
+		{"a > b && 1 / 0", 0},
+		{"a < b || 1 / 0", 1},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(ctx, mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+	for _, src := range []string{"a ? b", "a ? : b", "a < < b", "a &&"} {
+		if _, err := ParseExpression(src); err == nil {
+			t.Errorf("%s: want parse error", src)
+		}
+	}
+}
+
+func TestParsePowerAndUnary(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"2^3^2", 512},
+		{"(2^3)^2", 64},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^-1", 0.5},
+		{"--3", 3},
+		{"+3 - -3", 6},
+		{"-2*3", -6},
+		{"!0 + 1", 2},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(context.Background(), mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+	if _, ok := mustParse(t, "-x").(UnaryExprNode); !ok {
+		t.Error("-x should parse as UnaryExprNode")
+	}
+}
+
+func TestParseLegacyPrecedence(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.SetLegacyPrecedence(true)
+	for src, want := range map[string]float64{"2^3^2": 64, "-2^2": 4} {
+		expr, err := env.ParseExpression(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := env.Evaluate(context.Background(), expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("legacy %s = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	src := `max(a, 2.5) + -b * c`
+	expr := mustParse(t, src)
+	var spans []string
+	var visit func(node ExprNode)
+	visit = func(node ExprNode) {
+		span := SpanOf(node)
+		if !span.IsValid() {
+			t.Errorf("%T has no span", node)
+		} else {
+			spans = append(spans, src[span.Start:span.End])
+		}
+		switch n := node.(type) {
+		case OperatorExprNode:
+			visit(n.Lhs)
+			visit(n.Rhs)
+		case UnaryExprNode:
+			visit(n.Operand)
+		case FunCallerExprNode:
+			for _, arg := range n.Arg {
+				visit(arg)
+			}
+		}
+	}
+	visit(expr)
+	want := []string{src, "max(a, 2.5)", "a", "2.5", "-b * c", "-b", "b", "c"}
+	if strings.Join(spans, "|") != strings.Join(want, "|") {
+		t.Errorf("spans = %q, want %q", spans, want)
+	}
+}
+
+func TestParseStringSpans(t *testing.T) {
+	src := `concat("a b", x) + "s"`
+	var spans []string
+	Inspect(mustParse(t, src), func(node ExprNode) bool {
+		if _, ok := node.(StringExprNode); ok {
+			span := SpanOf(node)
+			if !span.IsValid() {
+				t.Errorf("%T has no span", node)
+				return true
+			}
+			spans = append(spans, src[span.Start:span.End])
+		}
+		return true
+	})
+	want := []string{`"a b"`, `"s"`}
+	if !equalStrings(spans, want) {
+		t.Errorf("string spans = %q, want %q", spans, want)
+	}
+}
@@ -0,0 +1,392 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+type opcode uint8
+
+const (
+	opConst        opcode = iota // 压入常量
+	opVar                        // 压入变量槽位
+	opAdd                        // 内置操作符
+	opSub                        //
+	opMul                        //
+	opDiv                        //
+	opPow                        //
+	opMod                        //
+	opNeg                        // 内置一元负号
+	opUnary                      // 自定义一元操作符
+	opOperator                   // 自定义操作符
+	opCallFloat                  // FloatFunc函数
+	opCallFunc                   // 普通DefFunc函数，参数转换为NumberExprNode
+	opJump                       // 跳转至slot
+	opJumpIfZero                 // 弹出栈顶，为0时跳转至slot
+	opShortCircuit               // 栈顶可确定结果时替换栈顶并跳转至slot
+)
+
+type instr struct {
+	op       opcode
+	val      float64
+	slot     int
+	argc     int
+	name     string
+	operator OperatorItem
+	fn       FloatFunc
+	def      DefFunc
+	short    ShortCircuitOperator
+	node     ExprNode // 出错时记录的节点
+}
+
+// Program 预编译的表达式，操作符、函数及变量槽位在编译期解析，适用于同一公式的大量重复计算
+// 内置操作符直接以float64运算，实现FloatFunc的函数调用不产生内存分配
+type Program struct {
+	code   []instr
+	vars   []string
+	slots  map[string]int
+	depth  int
+	strict bool
+	ctx    context.Context
+	pool   sync.Pool
+}
+
+// Compile 编译表达式
+func Compile(expr ExprNode) (*Program, error) {
+	return defaultEnv.Compile(expr)
+}
+
+// CompileContext 使用上下文中的运行环境及计算选项编译表达式
+func CompileContext(ctx context.Context, expr ExprNode) (*Program, error) {
+	return EnvironmentFrom(ctx).compile(ctx, expr)
+}
+
+// Compile 在当前环境中编译表达式
+func (e *Environment) Compile(expr ExprNode) (*Program, error) {
+	return e.compile(context.Background(), expr)
+}
+
+func (e *Environment) compile(ctx context.Context, expr ExprNode) (*Program, error) {
+	opts := OptionsFrom(ctx)
+	if opts.Precision != PrecisionFloat64 {
+		return nil, errors.New(fmt.Sprintf("precision mode %d is not supported by compile", opts.Precision))
+	}
+	c := &compiler{env: e, opts: opts, prog: &Program{
+		slots:  map[string]int{},
+		strict: opts.Strict,
+		ctx:    WithEnvironment(ctx, e),
+	}}
+	if err := c.compile(expr); err != nil {
+		return nil, err
+	}
+	p := c.prog
+	depth := p.depth
+	p.pool.New = func() any {
+		stack := make([]float64, depth)
+		return &stack
+	}
+	return p, nil
+}
+
+type compiler struct {
+	env  *Environment
+	opts Options
+	prog *Program
+	sp   int
+}
+
+func (c *compiler) emit(in instr, pop int) {
+	c.prog.code = append(c.prog.code, in)
+	c.sp -= pop
+	c.sp++
+	if c.sp > c.prog.depth {
+		c.prog.depth = c.sp
+	}
+}
+
+// jump 生成跳转指令，跳转位置由调用方回填
+func (c *compiler) jump(op opcode) int {
+	c.prog.code = append(c.prog.code, instr{op: op})
+	return len(c.prog.code) - 1
+}
+
+func (c *compiler) compile(expr ExprNode) error {
+	switch node := expr.(type) {
+
+	case NumberExprNode:
+		c.emit(instr{op: opConst, val: node.Val}, 0)
+
+	case ConstExprNode:
+		c.emit(instr{op: opConst, val: node.Val}, 0)
+
+	case VariableExprNode:
+		slot, ok := c.prog.slots[node.Val]
+		if !ok {
+			slot = len(c.prog.vars)
+			c.prog.slots[node.Val] = slot
+			c.prog.vars = append(c.prog.vars, node.Val)
+		}
+		c.emit(instr{op: opVar, slot: slot, name: node.Val, node: node}, 0)
+
+	case ConditionalExprNode:
+		if err := c.compile(node.Cond); err != nil {
+			return err
+		}
+		jz := c.jump(opJumpIfZero)
+		c.sp--
+		if err := c.compile(node.Then); err != nil {
+			return err
+		}
+		jmp := c.jump(opJump)
+		c.sp--
+		c.prog.code[jz].slot = len(c.prog.code)
+		if err := c.compile(node.Else); err != nil {
+			return err
+		}
+		c.prog.code[jmp].slot = len(c.prog.code)
+
+	case UnaryExprNode:
+		operator, _ := c.env.Operator(node.Op)
+		if _, ok := operator.(UnaryOperatorItem); !ok {
+			return &EvalError{Expr: node, Err: &OperatorError{Op: node.Op, Err: ErrUndefinedOperator}}
+		}
+		if err := c.compile(node.Operand); err != nil {
+			return err
+		}
+		in := instr{op: opUnary, name: node.Op, operator: operator, node: node}
+		if _, ok := operator.(*Minus); ok {
+			in.op = opNeg
+		}
+		c.emit(in, 1)
+
+	case OperatorExprNode:
+		operator, ok := c.env.Operator(node.Op)
+		if !ok {
+			return &EvalError{Expr: node, Err: &OperatorError{Op: node.Op, Err: ErrUndefinedOperator}}
+		}
+		if err := c.compile(node.Lhs); err != nil {
+			return err
+		}
+		short := -1
+		if s, ok := operator.(ShortCircuitOperator); ok {
+			short = c.jump(opShortCircuit)
+			c.prog.code[short].short = s
+		}
+		if err := c.compile(node.Rhs); err != nil {
+			return err
+		}
+		in := instr{op: opOperator, name: node.Op, operator: operator, node: node}
+		switch operator.(type) {
+		case *Plus:
+			in.op = opAdd
+		case *Minus:
+			in.op = opSub
+		case *Mul:
+			in.op = opMul
+		case *Div:
+			in.op = opDiv
+		case *Pow:
+			in.op = opPow
+		case *Mod:
+			in.op = opMod
+		}
+		c.emit(in, 2)
+		if short >= 0 {
+			c.prog.code[short].slot = len(c.prog.code)
+		}
+
+	case FunCallerExprNode:
+		def, ok := c.env.Func(node.Name)
+		if !ok {
+			return &EvalError{Expr: node, Err: &FuncError{Name: node.Name, Err: ErrUndefinedFunc}}
+		}
+		if def.Argc() >= 0 && def.Argc() != len(node.Arg) {
+			return &EvalError{Expr: node, Err: &FuncError{Name: node.Name, Err: errors.New(
+				fmt.Sprintf("parameters want %d but get %d", def.Argc(), len(node.Arg)))}}
+		}
+		for _, arg := range node.Arg {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		in := instr{op: opCallFunc, name: node.Name, argc: len(node.Arg), def: def, node: node}
+		// 非弧度计算时三角函数需读取计算选项
+		if fn, ok := def.(FloatFunc); ok && (c.opts.AngleUnit == Radian || !isAngleFunc(def)) {
+			in.op = opCallFloat
+			in.fn = fn
+		}
+		c.emit(in, len(node.Arg))
+
+	case StringExprNode:
+		return &EvalError{Expr: node, Err: &TypeError{Want: "number", Kinds: []ValueKind{StringKind}}}
+
+	default:
+		return errors.New(fmt.Sprintf("unknown expr node %T", expr))
+	}
+	return nil
+}
+
+// Vars 变量名称，顺序与Eval的参数槽位一致
+func (p *Program) Vars() []string {
+	vars := make([]string, len(p.vars))
+	copy(vars, p.vars)
+	return vars
+}
+
+// Slot 获取变量槽位，变量不存在时返回-1
+func (p *Program) Slot(name string) int {
+	if slot, ok := p.slots[name]; ok {
+		return slot
+	}
+	return -1
+}
+
+// Bind 按变量名称生成Eval所需的参数
+func (p *Program) Bind(values map[string]float64) ([]float64, error) {
+	vars := make([]float64, len(p.vars))
+	for i, name := range p.vars {
+		v, ok := values[name]
+		if !ok {
+			return nil, &UnboundVariableError{Name: name}
+		}
+		vars[i] = v
+	}
+	return vars, nil
+}
+
+// Eval 计算表达式，vars按Vars()的顺序传入变量值
+func (p *Program) Eval(vars []float64) (float64, error) {
+	if len(vars) < len(p.vars) {
+		return 0, &UnboundVariableError{Name: p.vars[len(vars)]}
+	}
+	sp := p.pool.Get().(*[]float64)
+	v, err := p.run(*sp, vars)
+	p.pool.Put(sp)
+	return v, err
+}
+
+func (p *Program) run(stack []float64, vars []float64) (float64, error) {
+	n := 0
+	for i := 0; i < len(p.code); i++ {
+		in := &p.code[i]
+		switch in.op {
+		case opJump:
+			i = in.slot - 1
+			continue
+		case opJumpIfZero:
+			n--
+			if stack[n] == 0 {
+				i = in.slot - 1
+			}
+			continue
+		case opShortCircuit:
+			if v, ok := in.short.ShortCircuit(stack[n-1]); ok {
+				stack[n-1] = v
+				i = in.slot - 1
+			}
+			continue
+		case opConst:
+			stack[n] = in.val
+			n++
+			continue
+		case opVar:
+			stack[n] = vars[in.slot]
+			n++
+			continue
+		case opNeg:
+			stack[n-1] = -stack[n-1]
+			continue
+		case opUnary:
+			v, err := evalUnary(in.operator, in.name, stack[n-1])
+			if err != nil {
+				return 0, &EvalError{Expr: in.node, Err: err}
+			}
+			if p.strict && !isFinite(v) {
+				return 0, &EvalError{Expr: in.node, Err: ErrNotFinite}
+			}
+			stack[n-1] = v
+			continue
+		case opCallFloat, opCallFunc:
+			args := stack[n-in.argc : n]
+			var v float64
+			var err error
+			if in.op == opCallFloat {
+				v, err = in.fn.CallFloat(args)
+				if err != nil {
+					err = &FuncError{Name: in.name, Err: err}
+				}
+			} else {
+				v, err = p.callFunc(in, args)
+			}
+			if err != nil {
+				var evalErr *EvalError
+				if errors.As(err, &evalErr) {
+					return 0, err
+				}
+				return 0, &EvalError{Expr: in.node, Err: err}
+			}
+			if p.strict && !isFinite(v) {
+				return 0, &EvalError{Expr: in.node, Err: ErrNotFinite}
+			}
+			n -= in.argc
+			stack[n] = v
+			n++
+			continue
+		}
+
+		a, b := stack[n-2], stack[n-1]
+		n--
+		switch in.op {
+		case opAdd:
+			stack[n-1] = a + b
+		case opSub:
+			stack[n-1] = a - b
+		case opMul:
+			stack[n-1] = a * b
+		case opDiv:
+			if b == 0 {
+				return 0, &EvalError{Expr: in.node, Err: &OperatorError{Op: in.name, Lhs: a, Rhs: b, Err: ErrDivisionByZero}}
+			}
+			stack[n-1] = a / b
+		case opPow:
+			stack[n-1] = math.Pow(a, b)
+		case opMod:
+			if int(b) == 0 {
+				return 0, &EvalError{Expr: in.node, Err: &OperatorError{Op: in.name, Lhs: a, Rhs: b, Err: ErrDivisionByZero}}
+			}
+			stack[n-1] = float64(int(a) % int(b))
+		case opOperator:
+			v, err := evalOperator(in.operator, in.name, a, b)
+			if err != nil {
+				return 0, &EvalError{Expr: in.node, Err: err}
+			}
+			stack[n-1] = v
+		}
+		if in.op <= opDiv && math.IsNaN(stack[n-1]) {
+			// NaN操作数及 Inf-Inf、0*Inf 等运算在Evaluate中以big.Float计算时返回错误，由内置操作符生成相同的错误
+			if _, err := evalOperator(in.operator, in.name, a, b); err != nil {
+				return 0, &EvalError{Expr: in.node, Err: err}
+			}
+		}
+		if p.strict && !isFinite(stack[n-1]) {
+			return 0, &EvalError{Expr: in.node, Err: ErrNotFinite}
+		}
+	}
+	return stack[0], nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// callFunc 调用未实现FloatFunc的函数，参数以NumberExprNode传入
+func (p *Program) callFunc(in *instr, args []float64) (float64, error) {
+	nodes := make([]ExprNode, len(args))
+	for i, v := range args {
+		nodes[i] = newNumber(v)
+	}
+	return evalFunc(p.ctx, in.def, in.name, nodes)
+}
@@ -0,0 +1,99 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+const benchExpr = "x * y + sin(x) / (1 + y^2) - max(x, y) % 3"
+
+func TestProgramEval(t *testing.T) {
+	for _, src := range []string{benchExpr, "x > y ? x : y", "x > 0 && y > 0", "-x + abs(y)"} {
+		expr := mustParse(t, src)
+		p, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		vars, err := p.Bind(map[string]float64{"x": 1.5, "y": -2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.Eval(vars)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		want, _ := Evaluate(varsCtx(map[string]any{"x": 1.5, "y": -2}), expr)
+		if got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("%s: program %v, evaluate %v", src, got, want)
+		}
+	}
+}
+
+func TestProgramEvalNaN(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		src  string
+		x, y float64
+	}{
+		{"x - y", inf, inf},
+		{"x + y", inf, -inf},
+		{"x * y", 0, inf},
+		{"x / y", inf, inf},
+		{"x + y", math.NaN(), 1},
+	}
+	for _, tt := range tests {
+		expr := mustParse(t, tt.src)
+		_, want := Evaluate(varsCtx(map[string]any{"x": tt.x, "y": tt.y}), expr)
+		if want == nil {
+			t.Fatalf("%s at x=%v y=%v: Evaluate returned no error", tt.src, tt.x, tt.y)
+		}
+		p, err := Compile(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.Eval([]float64{tt.x, tt.y})
+		if err == nil {
+			t.Errorf("%s at x=%v y=%v: program %v, evaluate error %v", tt.src, tt.x, tt.y, got, want)
+		} else if err.Error() != want.Error() {
+			t.Errorf("%s at x=%v y=%v: program error %v, evaluate error %v", tt.src, tt.x, tt.y, err, want)
+		}
+	}
+}
+
+func TestProgramEvalAllocs(t *testing.T) {
+	p, err := Compile(mustParse(t, benchExpr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := []float64{1.5, -2}
+	p.Eval(vars)
+	if n := testing.AllocsPerRun(100, func() {
+		p.Eval(vars)
+	}); n != 0 {
+		t.Errorf("Eval allocates %v times per run", n)
+	}
+}
+
+func BenchmarkProgramEval(b *testing.B) {
+	p, err := Compile(mustParse(b, benchExpr))
+	if err != nil {
+		b.Fatal(err)
+	}
+	vars := []float64{1.5, -2}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Eval(vars)
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	expr := mustParse(b, benchExpr)
+	ctx := WithParameter(context.Background(), NewParameter(map[string]any{"x": 1.5, "y": -2}, nil))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Evaluate(ctx, expr)
+	}
+}
@@ -0,0 +1,144 @@
+package mathastc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Resolve 获取变量值，支持以点号访问Vars中结构体、map及切片的成员，如 order.total
+func (p *Parameter) Resolve(ctx context.Context, name string) (any, error) {
+	if v, ok := p.Vars[name]; ok {
+		return v, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if root, ok := p.Vars[name[:i]]; ok {
+			if v, ok := lookupPath(reflect.ValueOf(root), name[i+1:]); ok {
+				return v, nil
+			}
+		}
+	}
+	return nil, &UnboundVariableError{Name: name}
+}
+
+// ResolverFunc 函数形式的VariableResolver
+type ResolverFunc func(ctx context.Context, name string) (any, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, name string) (any, error) {
+	return f(ctx, name)
+}
+
+// MapResolver 以map提供变量值，如数据库查询的一行记录，支持点号路径
+type MapResolver map[string]any
+
+func (m MapResolver) Resolve(ctx context.Context, name string) (any, error) {
+	if v, ok := lookupPath(reflect.ValueOf(map[string]any(m)), name); ok {
+		return v, nil
+	}
+	return nil, &UnboundVariableError{Name: name}
+}
+
+// ChainResolver 依次查找变量，前一个返回UnboundVariableError时继续查找下一个
+type ChainResolver []VariableResolver
+
+func (c ChainResolver) Resolve(ctx context.Context, name string) (any, error) {
+	for _, r := range c {
+		v, err := r.Resolve(ctx, name)
+		var unbound *UnboundVariableError
+		if errors.As(err, &unbound) {
+			continue
+		}
+		return v, err
+	}
+	return nil, &UnboundVariableError{Name: name}
+}
+
+// structResolver 以反射读取结构体字段
+type structResolver struct {
+	v reflect.Value
+}
+
+// NewStructResolver 以结构体字段提供变量值，字段名称取`expr`标签，未设置时为字段名
+// 变量名称支持点号访问嵌套的结构体、map及切片，如 order.items.0.price
+func NewStructResolver(v any) VariableResolver {
+	return &structResolver{v: reflect.ValueOf(v)}
+}
+
+func (s *structResolver) Resolve(ctx context.Context, name string) (any, error) {
+	if v, ok := lookupPath(s.v, name); ok {
+		return v, nil
+	}
+	return nil, &UnboundVariableError{Name: name}
+}
+
+// NewJSONResolver 以JSON文档提供变量值，变量名称为点号分隔的路径，如 order.total
+func NewJSONResolver(data []byte) (VariableResolver, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &structResolver{v: reflect.ValueOf(doc)}, nil
+}
+
+// lookupPath 按点号分隔的路径查找成员
+func lookupPath(v reflect.Value, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		var ok bool
+		if v, ok = lookupMember(v, key); !ok {
+			return nil, false
+		}
+	}
+	v = indirect(v)
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+func lookupMember(v reflect.Value, key string) (reflect.Value, bool) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag := f.Tag.Get("expr"); tag != "" {
+				name = tag
+			}
+			if name == key {
+				return v.Field(i), true
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		e := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		return e, e.IsValid()
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= v.Len() {
+			return reflect.Value{}, false
+		}
+		return v.Index(i), true
+	}
+	return reflect.Value{}, false
+}
+
+// indirect 解引用指针及接口
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type order struct {
+	Total float64 `expr:"total"`
+	Items []item  `expr:"items"`
+	Note  string
+}
+
+type item struct {
+	Price float64 `expr:"price"`
+	Count int     `expr:"count"`
+}
+
+func evalResolver(t *testing.T, r VariableResolver, src string) (float64, error) {
+	t.Helper()
+	return Evaluate(WithOptions(context.Background(), Options{Resolver: r}), mustParse(t, src))
+}
+
+func TestStructResolver(t *testing.T) {
+	r := NewStructResolver(&order{Total: 10, Items: []item{{Price: 2.5, Count: 2}}})
+	v, err := evalResolver(t, r, "total - items.0.price * items.0.count")
+	if err != nil || v != 5 {
+		t.Errorf("struct resolver = %v, %v", v, err)
+	}
+	_, err = evalResolver(t, r, "items.1.price")
+	var unbound *UnboundVariableError
+	if !errors.As(err, &unbound) || unbound.Name != "items.1.price" {
+		t.Errorf("missing member: %v", err)
+	}
+}
+
+func TestJSONResolver(t *testing.T) {
+	r, err := NewJSONResolver([]byte(`{"order": {"total": 12, "tax": {"rate": 0.5}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := evalResolver(t, r, "order.total * order.tax.rate")
+	if err != nil || v != 6 {
+		t.Errorf("json resolver = %v, %v", v, err)
+	}
+}
+
+func TestChainResolver(t *testing.T) {
+	r := ChainResolver{
+		MapResolver{"a": 1, "expr": "a + 1"},
+		ResolverFunc(func(ctx context.Context, name string) (any, error) {
+			if name == "b" {
+				return 10, nil
+			}
+			return nil, &UnboundVariableError{Name: name}
+		}),
+	}
+	v, err := evalResolver(t, r, "a + b + expr")
+	if err != nil || v != 13 {
+		t.Errorf("chain resolver = %v, %v", v, err)
+	}
+	failing := ResolverFunc(func(ctx context.Context, name string) (any, error) {
+		return nil, errors.New("connection lost")
+	})
+	if _, err := evalResolver(t, ChainResolver{failing, MapResolver{"a": 1}}, "a"); err == nil {
+		t.Error("want resolver error")
+	}
+}
+
+func TestResolverValue(t *testing.T) {
+	r := MapResolver{"n": IntValue(10), "s": StringValue("ab")}
+	v, err := evalResolver(t, r, "n + 1")
+	if err != nil || v != 11 {
+		t.Errorf("IntValue in Evaluate = %v, %v", v, err)
+	}
+	ctx := WithOptions(context.Background(), Options{Resolver: r})
+	got, err := EvaluateValue(ctx, mustParse(t, "n * 2"))
+	if i, ok := got.Int(); err != nil || !ok || i != 20 {
+		t.Errorf("IntValue in EvaluateValue = %v, %v", got, err)
+	}
+	got, err = EvaluateValue(ctx, mustParse(t, "len(s)"))
+	if i, ok := got.Int(); err != nil || !ok || i != 2 {
+		t.Errorf("StringValue in EvaluateValue = %v, %v", got, err)
+	}
+}
+
+func TestParameterResolve(t *testing.T) {
+	p := NewParameter(map[string]any{"order": map[string]any{"total": 3}}, nil)
+	v, err := p.Resolve(context.Background(), "order.total")
+	if err != nil || v != 3 {
+		t.Errorf("Parameter.Resolve = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,317 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Sheet 命名公式表，公式之间通过变量名引用，按依赖关系拓扑排序计算
+// 修改公式后仅重新计算受影响的单元，单元的计算异常不影响其他单元
+type Sheet struct {
+	mu    sync.Mutex
+	env   *Environment
+	cells map[string]*cell
+	names []string // 定义顺序
+}
+
+type cell struct {
+	src   string
+	expr  ExprNode
+	deps  []string
+	value float64
+	err   error
+	dirty bool
+}
+
+// CellError 引用的单元计算异常
+type CellError struct {
+	Name string
+	Err  error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("cell `%s` failed: %v", e.Name, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// NewSheet 创建公式表，env为空时使用默认运行环境
+func NewSheet(env *Environment) *Sheet {
+	if env == nil {
+		env = defaultEnv
+	}
+	return &Sheet{env: env, cells: map[string]*cell{}}
+}
+
+// Set 设置公式，返回公式的解析异常，解析异常同样记录为单元的计算异常
+func (s *Sheet) Set(name string, formula string) error {
+	expr, err := s.env.ParseExpression(formula)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.cell(name)
+	c.src = formula
+	c.expr, c.deps = nil, nil
+	if err == nil {
+		c.expr = expr
+		for _, v := range Variables(expr) {
+			c.deps = append(c.deps, v.Name)
+		}
+	}
+	s.invalidate(name)
+	c.err = err
+	return err
+}
+
+// SetValue 设置数值单元
+func (s *Sheet) SetValue(name string, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.cell(name)
+	c.src = Float64ToStr(value)
+	c.expr = newNumber(value)
+	c.deps = nil
+	c.err = nil
+	s.invalidate(name)
+}
+
+// Remove 移除单元，引用该单元的公式将在下次计算时从上下文中查找同名变量
+func (s *Sheet) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cells[name]; !ok {
+		return
+	}
+	s.invalidate(name)
+	delete(s.cells, name)
+	for i, n := range s.names {
+		if n == name {
+			s.names = append(s.names[:i], s.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// Invalidate 标记外部变量已变更，引用这些变量的单元将在下次计算时重新计算
+func (s *Sheet) Invalidate(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		s.invalidate(name)
+	}
+}
+
+func (s *Sheet) cell(name string) *cell {
+	c, ok := s.cells[name]
+	if !ok {
+		c = &cell{}
+		s.cells[name] = c
+		s.names = append(s.names, name)
+	}
+	return c
+}
+
+// invalidate 标记单元及依赖该单元的全部单元需要重新计算
+func (s *Sheet) invalidate(name string) {
+	if c, ok := s.cells[name]; ok {
+		if c.dirty {
+			return
+		}
+		c.dirty = true
+	}
+	for _, n := range s.dependents(name) {
+		s.invalidate(n)
+	}
+}
+
+// Names 单元名称，按定义顺序
+func (s *Sheet) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.names...)
+}
+
+// Formula 获取单元公式
+func (s *Sheet) Formula(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cells[name]
+	if !ok {
+		return "", false
+	}
+	return c.src, true
+}
+
+// Dependencies 单元直接引用的变量
+func (s *Sheet) Dependencies(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cells[name]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, c.deps...)
+}
+
+// Dependents 直接引用name的单元
+func (s *Sheet) Dependents(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dependents(name)
+}
+
+func (s *Sheet) dependents(name string) []string {
+	var r []string
+	for _, n := range s.names {
+		if containsStr(s.cells[n].deps, name) {
+			r = append(r, n)
+		}
+	}
+	return r
+}
+
+// Value 获取单元最近一次计算的值
+func (s *Sheet) Value(name string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cells[name]
+	if !ok {
+		return 0, &UnboundVariableError{Name: name}
+	}
+	if c.dirty && c.err == nil {
+		return 0, errors.New(fmt.Sprintf("cell `%s` is not calculated", name))
+	}
+	return c.value, c.err
+}
+
+// Errors 计算异常的单元
+func (s *Sheet) Errors() map[string]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := map[string]error{}
+	for name, c := range s.cells {
+		if c.err != nil {
+			errs[name] = c.err
+		}
+	}
+	return errs
+}
+
+// Order 单元的计算顺序，循环引用的单元不在其中
+func (s *Sheet) Order() ([]string, map[string]*CycleError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sort()
+}
+
+// sort 拓扑排序，返回计算顺序及循环引用的单元
+func (s *Sheet) sort() ([]string, map[string]*CycleError) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var order, stack []string
+	cycles := map[string]*CycleError{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		c, ok := s.cells[name]
+		if !ok {
+			return
+		}
+		switch state[name] {
+		case visiting:
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					chain := append(append([]string{}, stack[i:]...), name)
+					for _, n := range stack[i:] {
+						if _, ok := cycles[n]; !ok {
+							cycles[n] = &CycleError{Chain: chain}
+						}
+					}
+					break
+				}
+			}
+			return
+		case visited:
+			return
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range c.deps {
+			visit(dep)
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		if _, ok := cycles[name]; !ok {
+			order = append(order, name)
+		}
+	}
+	for _, name := range s.names {
+		visit(name)
+	}
+	return order, cycles
+}
+
+// Recalc 按依赖顺序重新计算需要更新的单元，返回重新计算的单元
+// 单元之外的变量从ctx中的Options.Resolver或Parameter查找
+func (s *Sheet) Recalc(ctx context.Context) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, cycles := s.sort()
+	var recalculated []string
+	for name, err := range cycles {
+		c := s.cells[name]
+		if c.dirty {
+			c.value, c.err, c.dirty = 0, err, false
+			recalculated = append(recalculated, name)
+		}
+	}
+
+	opts := OptionsFrom(ctx)
+	opts.Resolver = &sheetResolver{sheet: s, fallback: opts.Resolver}
+	ctx = WithOptions(WithEnvironment(ctx, s.env), opts)
+	for _, name := range order {
+		c := s.cells[name]
+		if !c.dirty {
+			continue
+		}
+		c.dirty = false
+		recalculated = append(recalculated, name)
+		if c.expr == nil {
+			// 保留解析异常
+			continue
+		}
+		c.value, c.err = Evaluate(ctx, c.expr)
+	}
+	return recalculated
+}
+
+// sheetResolver 优先从公式表中查找变量
+type sheetResolver struct {
+	sheet    *Sheet
+	fallback VariableResolver
+}
+
+func (r *sheetResolver) Resolve(ctx context.Context, name string) (any, error) {
+	if c, ok := r.sheet.cells[name]; ok {
+		if c.err != nil {
+			return nil, &CellError{Name: name, Err: c.err}
+		}
+		return c.value, nil
+	}
+	if r.fallback != nil {
+		return r.fallback.Resolve(ctx, name)
+	}
+	parameter, err := GetCtxParameter(ctx)
+	if err != nil {
+		return nil, &UnboundVariableError{Name: name}
+	}
+	return parameter.Resolve(ctx, name)
+}
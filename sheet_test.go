@@ -0,0 +1,129 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+)
+
+func sheetValue(t *testing.T, s *Sheet, name string) float64 {
+	t.Helper()
+	v, err := s.Value(name)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return v
+}
+
+func TestSheetRecalc(t *testing.T) {
+	s := NewSheet(nil)
+	s.Set("total", "subtotal + tax")
+	s.Set("tax", "subtotal * rate")
+	s.Set("subtotal", "price * qty")
+	s.SetValue("price", 10)
+	s.SetValue("qty", 3)
+	ctx := varsCtx(map[string]any{"rate": 0.5})
+
+	if got := s.Recalc(ctx); len(got) != 5 {
+		t.Errorf("first Recalc = %v", got)
+	}
+	if v := sheetValue(t, s, "total"); v != 45 {
+		t.Errorf("total = %v, want 45", v)
+	}
+	order, cycles := s.Order()
+	if len(cycles) != 0 || indexOf(order, "subtotal") > indexOf(order, "tax") || indexOf(order, "tax") > indexOf(order, "total") {
+		t.Errorf("order = %v, cycles = %v", order, cycles)
+	}
+
+	s.SetValue("qty", 4)
+	got := s.Recalc(ctx)
+	sort.Strings(got)
+	if want := []string{"qty", "subtotal", "tax", "total"}; !equalStrings(got, want) {
+		t.Errorf("Recalc after qty change = %v, want %v", got, want)
+	}
+	if v := sheetValue(t, s, "total"); v != 60 {
+		t.Errorf("total = %v, want 60", v)
+	}
+	if got := s.Recalc(ctx); len(got) != 0 {
+		t.Errorf("Recalc without changes = %v", got)
+	}
+
+	s.Invalidate("rate")
+	got = s.Recalc(varsCtx(map[string]any{"rate": 0}))
+	sort.Strings(got)
+	if want := []string{"tax", "total"}; !equalStrings(got, want) {
+		t.Errorf("Recalc after Invalidate = %v, want %v", got, want)
+	}
+	if v := sheetValue(t, s, "total"); v != 40 {
+		t.Errorf("total = %v, want 40", v)
+	}
+}
+
+func TestSheetCycle(t *testing.T) {
+	s := NewSheet(nil)
+	s.Set("a", "b + 1")
+	s.Set("b", "a + 1")
+	s.Set("c", "a * 2")
+	s.Set("d", "1")
+	s.Recalc(context.Background())
+
+	var cycle *CycleError
+	if _, err := s.Value("a"); !errors.As(err, &cycle) {
+		t.Errorf("a: %v", err)
+	}
+	var cellErr *CellError
+	if _, err := s.Value("c"); !errors.As(err, &cellErr) || cellErr.Name != "a" {
+		t.Errorf("c: %v", err)
+	}
+	if v := sheetValue(t, s, "d"); v != 1 {
+		t.Errorf("d = %v", v)
+	}
+	if errs := s.Errors(); len(errs) != 3 {
+		t.Errorf("Errors = %v", errs)
+	}
+
+	// 打破循环后恢复计算
+	s.Set("b", "2")
+	s.Recalc(context.Background())
+	if v := sheetValue(t, s, "c"); v != 6 {
+		t.Errorf("c = %v, want 6", v)
+	}
+}
+
+func TestSheetParseError(t *testing.T) {
+	s := NewSheet(nil)
+	if err := s.Set("a", "1 +"); err == nil {
+		t.Error("want parse error")
+	}
+	s.Set("b", "a + 1")
+	s.Recalc(context.Background())
+	if _, err := s.Value("a"); err == nil {
+		t.Error("a: want parse error")
+	}
+	var cellErr *CellError
+	if _, err := s.Value("b"); !errors.As(err, &cellErr) {
+		t.Errorf("b: %v", err)
+	}
+}
+
+func indexOf(s []string, v string) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
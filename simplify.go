@@ -0,0 +1,570 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"math/big"
+)
+
+// SimplifyOptions 化简选项
+type SimplifyOptions struct {
+	FoldConsts bool         // 将pi、e等常量折叠为数值
+	FoldFuncs  bool         // 计算参数均为数值的函数调用
+	Collect    bool         // 合并同类项及同底数幂，结果代数等价但可能改变浮点计算结果
+	Env        *Environment // 函数及操作符所在环境，为空时使用默认环境
+}
+
+// Simplify 化简表达式：折叠常量子树，应用恒等/零元规则，规范化一元负号
+// 默认仅应用不改变计算结果的规则，在原表达式有定义的输入上化简结果的计算结果与原表达式一致
+// 开启SimplifyOptions.Collect时合并同类项及同底数幂，结果仅代数等价：重组求和顺序、合并同类项及同底数幂会改变舍入，
+// 如 a + 1e16 - 1e16 化简为 a，x + y - x 化简为 y，(x^0.5)^2 化简为 x
+// 四则运算及整数次幂仅折叠float64可精确表示的结果，如 0.1 + 0.2 保持不变；函数调用按弧度折叠
+func Simplify(expr ExprNode, opts *SimplifyOptions) ExprNode {
+	return SimplifyContext(context.Background(), expr, opts)
+}
+
+// SimplifyContext 使用上下文中的运行环境及计算选项化简表达式，函数调用按Options.AngleUnit折叠
+// 非float64精度模式下仅折叠可精确表示的四则运算及整数次幂，不折叠函数调用及自定义操作符
+func SimplifyContext(ctx context.Context, expr ExprNode, opts *SimplifyOptions) ExprNode {
+	s := &simplifier{env: EnvironmentFrom(ctx)}
+	if opts != nil {
+		s.opts = *opts
+		if opts.Env != nil {
+			s.env = opts.Env
+		}
+	}
+	s.ctx = WithEnvironment(ctx, s.env)
+	s.float = OptionsFrom(ctx).Precision == PrecisionFloat64
+	return s.simplify(expr)
+}
+
+// Simplify 在当前环境中化简表达式
+func (e *Environment) Simplify(expr ExprNode, opts *SimplifyOptions) ExprNode {
+	o := SimplifyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.Env = e
+	return Simplify(expr, &o)
+}
+
+type simplifier struct {
+	opts  SimplifyOptions
+	env   *Environment
+	ctx   context.Context
+	float bool // float64精度模式，可折叠任意运算结果
+}
+
+// term 加法项 coef * expr，expr为空时表示常数项
+type term struct {
+	coef float64
+	expr ExprNode
+}
+
+// factor 乘法因子 base ^ exp
+type factor struct {
+	base ExprNode
+	exp  ExprNode
+}
+
+func (s *simplifier) simplify(expr ExprNode) ExprNode {
+	switch node := expr.(type) {
+
+	case NumberExprNode:
+		if node.Str == "" {
+			return newNumber(node.Val)
+		}
+		return node
+
+	case ConditionalExprNode:
+		c := s.simplify(node.Cond)
+		t := s.simplify(node.Then)
+		e := s.simplify(node.Else)
+		if n, ok := c.(NumberExprNode); ok {
+			if n.Val != 0 {
+				return t
+			}
+			return e
+		}
+		return ConditionalExprNode{Cond: c, Then: t, Else: e}
+
+	case ConstExprNode:
+		if s.opts.FoldConsts {
+			return newNumber(node.Val)
+		}
+		return node
+
+	case FunCallerExprNode:
+		args := make([]ExprNode, len(node.Arg))
+		numeric := true
+		for i, arg := range node.Arg {
+			args[i] = s.simplify(arg)
+			_, ok := args[i].(NumberExprNode)
+			numeric = numeric && ok
+		}
+		call := FunCallerExprNode{Name: node.Name, Arg: args}
+		if s.opts.FoldFuncs && numeric && s.float {
+			if v, err := Evaluate(s.ctx, call); err == nil {
+				return newNumber(v)
+			}
+		}
+		return call
+
+	case UnaryExprNode:
+		x := s.simplify(node.Operand)
+		switch {
+		case node.Op == "-" && s.isBuiltin("-"):
+			return s.negate(x)
+		case node.Op == "+" && s.isBuiltin("+"):
+			return x
+		}
+		if n, ok := x.(NumberExprNode); ok && s.float {
+			operator, _ := s.env.Operator(node.Op)
+			if v, err := evalUnary(operator, node.Op, n.Val); err == nil {
+				return newNumber(v)
+			}
+		}
+		return UnaryExprNode{Op: node.Op, Operand: x}
+
+	case OperatorExprNode:
+		if isUnaryMinus(node) && s.isBuiltin("-") {
+			return s.negate(s.simplify(node.Rhs))
+		}
+		l := node.Lhs
+		if !isPrefixOperand(l) {
+			l = s.simplify(l)
+		}
+		r := s.simplify(node.Rhs)
+		if !s.isBuiltin(node.Op) {
+			return s.fold(node.Op, l, r)
+		}
+		switch node.Op {
+		case "+", "-":
+			if s.opts.Collect {
+				return s.sum(newBinary(node.Op, l, r))
+			}
+			return s.add(node.Op, l, r)
+		case "*":
+			if s.opts.Collect {
+				return s.product(newBinary("*", l, r))
+			}
+			return s.mul(l, r)
+		case "/":
+			return s.quotient(l, r)
+		case "^":
+			return s.power(l, r)
+		}
+		return s.fold(node.Op, l, r)
+	}
+
+	return expr
+}
+
+// isBuiltin 判断操作符是否为内置实现，自定义操作符不应用代数规则
+func (s *simplifier) isBuiltin(op string) bool {
+	return isBuiltinOperator(s.env, op)
+}
+
+func isBuiltinOperator(env *Environment, op string) bool {
+	o, ok := env.Operator(op)
+	if !ok {
+		return false
+	}
+	switch o.(type) {
+	case *Plus:
+		return op == "+"
+	case *Minus:
+		return op == "-"
+	case *Mul:
+		return op == "*"
+	case *Div:
+		return op == "/"
+	case *Pow:
+		return op == "^"
+	case *Mod:
+		return op == "%"
+	}
+	return false
+}
+
+// fold 操作数均为数值时计算结果，运算异常或结果不精确时保留原节点
+func (s *simplifier) fold(op string, l ExprNode, r ExprNode) ExprNode {
+	ln, lok := l.(NumberExprNode)
+	rn, rok := r.(NumberExprNode)
+	if lok && rok {
+		operator, _ := s.env.Operator(op)
+		if v, err := evalOperator(operator, op, ln.Val, rn.Val); err == nil && s.exact(op, ln.Val, rn.Val, v) {
+			return newNumber(v)
+		}
+	}
+	return newBinary(op, l, r)
+}
+
+// exact 判断运算结果是否可以折叠，内置四则运算及整数次幂需结果精确，其他运算仅在float64精度模式下折叠
+func (s *simplifier) exact(op string, a float64, b float64, r float64) bool {
+	switch op {
+	case "+", "-", "*", "/", "^":
+		if s.isBuiltin(op) {
+			return exactResult(op, a, b, r)
+		}
+	}
+	return s.float
+}
+
+// maxExactPow 校验整数次幂结果的最大指数
+const maxExactPow = 64
+
+// exactResult 以有理数校验float64运算结果是否精确
+func exactResult(op string, a float64, b float64, r float64) bool {
+	x, y, z := exactRat(a), exactRat(b), exactRat(r)
+	if x == nil || y == nil || z == nil {
+		return false
+	}
+	switch op {
+	case "+":
+		x.Add(x, y)
+	case "-":
+		x.Sub(x, y)
+	case "*":
+		x.Mul(x, y)
+	case "/":
+		if y.Sign() == 0 {
+			return false
+		}
+		x.Quo(x, y)
+	case "^":
+		if !y.IsInt() || math.Abs(b) > maxExactPow || (x.Sign() == 0 && b < 0) {
+			return false
+		}
+		p := big.NewRat(1, 1)
+		for i := 0; i < int(math.Abs(b)); i++ {
+			p.Mul(p, x)
+		}
+		if b < 0 {
+			p.Inv(p)
+		}
+		x = p
+	default:
+		return false
+	}
+	return x.Cmp(z) == 0
+}
+
+func exactRat(f float64) *big.Rat {
+	if !isFinite(f) {
+		return nil
+	}
+	return new(big.Rat).SetFloat64(f)
+}
+
+// negate 规范化取反：-(-x) => x，-(2) => -2，-(2*x) => -2*x
+func (s *simplifier) negate(expr ExprNode) ExprNode {
+	if x, ok := negOperand(expr); ok {
+		return x
+	}
+	switch node := expr.(type) {
+	case NumberExprNode:
+		return newNumber(-node.Val)
+	case OperatorExprNode:
+		if node.Op == "*" && s.opts.Collect {
+			coef := 1.0
+			fs := s.factors(node, &coef, nil)
+			return s.buildProduct(-coef, fs)
+		}
+		// 仅改变数值系数的符号不影响舍入
+		if n, ok := node.Lhs.(NumberExprNode); ok && node.Op == "*" {
+			return s.mul(newNumber(-n.Val), node.Rhs)
+		}
+	}
+	return newNeg(expr)
+}
+
+// add 加减法的恒等规则，不重组求和顺序
+func (s *simplifier) add(op string, l ExprNode, r ExprNode) ExprNode {
+	switch {
+	case isZeroNode(r):
+		return l
+	case isZeroNode(l):
+		if op == "+" {
+			return r
+		}
+		return s.negate(r)
+	case op == "-" && EqualExpr(l, r):
+		return newNumber(0)
+	}
+	// a + -b => a - b，a - -b => a + b
+	inv := "-"
+	if op == "-" {
+		inv = "+"
+	}
+	if x, ok := negOperand(r); ok {
+		return newBinary(inv, l, x)
+	}
+	if n, ok := r.(NumberExprNode); ok && n.Val < 0 {
+		if _, ok := l.(NumberExprNode); !ok {
+			return newBinary(inv, l, newNumber(-n.Val))
+		}
+	}
+	return s.fold(op, l, r)
+}
+
+// mul 乘法的恒等/零元规则，不合并系数及同底数幂
+func (s *simplifier) mul(l ExprNode, r ExprNode) ExprNode {
+	switch {
+	case isZeroNode(l) || isZeroNode(r):
+		return newNumber(0)
+	case isOneNode(l):
+		return r
+	case isOneNode(r):
+		return l
+	case isMinusOneNode(l):
+		return s.negate(r)
+	case isMinusOneNode(r):
+		return s.negate(l)
+	}
+	// (-a) * (-b) => a * b
+	if a, ok := negOperand(l); ok {
+		if b, ok := negOperand(r); ok {
+			return s.mul(a, b)
+		}
+	}
+	return s.fold("*", l, r)
+}
+
+func isMinusOneNode(expr ExprNode) bool {
+	n, ok := expr.(NumberExprNode)
+	return ok && n.Val == -1
+}
+
+// sum 合并同类项，常数项置于末尾
+// 各项按代数规则重组并合并，浮点计算结果可能与原求和顺序不同
+func (s *simplifier) sum(expr ExprNode) ExprNode {
+	var merged, constants []term
+	constant := 0.0
+	for _, t := range s.terms(expr, 1, nil) {
+		if t.expr == nil {
+			// 求和不精确的常数项单独保留
+			if c := constant + t.coef; exactResult("+", constant, t.coef, c) {
+				constant = c
+			} else {
+				constants = append(constants, t)
+			}
+			continue
+		}
+		found := false
+		for i := range merged {
+			if c := merged[i].coef + t.coef; exactResult("+", merged[i].coef, t.coef, c) && s.sameFactors(merged[i].expr, t.expr) {
+				merged[i].coef = c
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, t)
+		}
+	}
+	if constant != 0 {
+		merged = append(merged, term{coef: constant})
+	}
+	merged = append(merged, constants...)
+
+	var r ExprNode
+	for _, t := range merged {
+		if t.coef == 0 {
+			continue
+		}
+		if r == nil {
+			r = s.termExpr(t.coef, t.expr)
+		} else if t.coef < 0 {
+			r = newBinary("-", r, s.termExpr(-t.coef, t.expr))
+		} else {
+			r = newBinary("+", r, s.termExpr(t.coef, t.expr))
+		}
+	}
+	if r == nil {
+		return newNumber(0)
+	}
+	return r
+}
+
+func (s *simplifier) terms(expr ExprNode, sign float64, ts []term) []term {
+	if x, ok := negOperand(expr); ok {
+		return s.terms(x, -sign, ts)
+	}
+	switch node := expr.(type) {
+	case NumberExprNode:
+		return append(ts, term{coef: sign * node.Val})
+	case OperatorExprNode:
+		switch node.Op {
+		case "+":
+			ts = s.terms(node.Lhs, sign, ts)
+			return s.terms(node.Rhs, sign, ts)
+		case "-":
+			ts = s.terms(node.Lhs, sign, ts)
+			return s.terms(node.Rhs, -sign, ts)
+		case "*":
+			coef := 1.0
+			fs := s.factors(node, &coef, nil)
+			return append(ts, term{coef: sign * coef, expr: s.buildProduct(1, fs)})
+		}
+	}
+	return append(ts, term{coef: sign, expr: expr})
+}
+
+// sameFactors 判断两个乘积的因子是否相同，忽略因子顺序
+func (s *simplifier) sameFactors(a ExprNode, b ExprNode) bool {
+	if EqualExpr(a, b) {
+		return true
+	}
+	ca, cb := 1.0, 1.0
+	fa := s.factors(a, &ca, nil)
+	fb := s.factors(b, &cb, nil)
+	if ca != cb || len(fa) != len(fb) {
+		return false
+	}
+	for _, x := range fa {
+		found := false
+		for _, y := range fb {
+			if EqualExpr(x.base, y.base) && EqualExpr(x.exp, y.exp) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *simplifier) termExpr(coef float64, expr ExprNode) ExprNode {
+	if expr == nil {
+		return newNumber(coef)
+	}
+	c := 1.0
+	return s.buildProduct(coef, s.factors(expr, &c, nil))
+}
+
+// product 合并数值系数及同底数幂
+func (s *simplifier) product(expr ExprNode) ExprNode {
+	coef := 1.0
+	fs := s.factors(expr, &coef, nil)
+	return s.buildProduct(coef, fs)
+}
+
+func (s *simplifier) factors(expr ExprNode, coef *float64, fs []factor) []factor {
+	if x, ok := negOperand(expr); ok {
+		*coef = -*coef
+		return s.factors(x, coef, fs)
+	}
+	switch node := expr.(type) {
+	case NumberExprNode:
+		// 乘积不精确的数值保留为因子
+		if c := *coef * node.Val; exactResult("*", *coef, node.Val, c) {
+			*coef = c
+			return fs
+		}
+		return append(fs, factor{base: node, exp: newNumber(1)})
+	case OperatorExprNode:
+		switch node.Op {
+		case "*":
+			fs = s.factors(node.Lhs, coef, fs)
+			return s.factors(node.Rhs, coef, fs)
+		case "^":
+			return s.addFactor(fs, node.Lhs, node.Rhs)
+		}
+	}
+	return s.addFactor(fs, expr, newNumber(1))
+}
+
+func (s *simplifier) addFactor(fs []factor, base ExprNode, exp ExprNode) []factor {
+	for i := range fs {
+		if EqualExpr(fs[i].base, base) {
+			fs[i].exp = s.sum(newBinary("+", fs[i].exp, exp))
+			return fs
+		}
+	}
+	return append(fs, factor{base: base, exp: exp})
+}
+
+// buildProduct 生成 coef * f1 * f2 ...，系数为0时返回0
+func (s *simplifier) buildProduct(coef float64, fs []factor) ExprNode {
+	if coef == 0 {
+		return newNumber(0)
+	}
+	var r ExprNode
+	if coef != 1 && coef != -1 {
+		r = newNumber(coef)
+	}
+	for _, f := range fs {
+		if isZeroNode(f.exp) {
+			continue
+		}
+		p := f.base
+		if !isOneNode(f.exp) {
+			p = newBinary("^", f.base, f.exp)
+		}
+		if r == nil {
+			r = p
+		} else {
+			r = newBinary("*", r, p)
+		}
+	}
+	if r == nil {
+		return newNumber(coef)
+	}
+	if coef == -1 {
+		return newNeg(r)
+	}
+	return r
+}
+
+func (s *simplifier) quotient(l ExprNode, r ExprNode) ExprNode {
+	rn, rok := r.(NumberExprNode)
+	switch {
+	case rok && rn.Val == 0:
+		return newBinary("/", l, r)
+	case isOneNode(r):
+		return l
+	case isZeroNode(l):
+		return newNumber(0)
+	case EqualExpr(l, r):
+		return newNumber(1)
+	}
+	if ln, ok := l.(NumberExprNode); ok && rok {
+		return s.fold("/", ln, rn)
+	}
+	if rok && s.opts.Collect {
+		// (6*x)/3 => 2*x
+		if node, ok := l.(OperatorExprNode); ok && node.Op == "*" {
+			coef := 1.0
+			fs := s.factors(node, &coef, nil)
+			if c := coef / rn.Val; coef != 1 && exactResult("/", coef, rn.Val, c) {
+				return s.buildProduct(c, fs)
+			}
+		}
+	}
+	return newBinary("/", l, r)
+}
+
+func (s *simplifier) power(l ExprNode, r ExprNode) ExprNode {
+	ln, lok := l.(NumberExprNode)
+	rn, rok := r.(NumberExprNode)
+	switch {
+	case lok && rok:
+		return s.fold("^", l, r)
+	case isZeroNode(r):
+		return newNumber(1)
+	case isOneNode(r):
+		return l
+	case isOneNode(l):
+		return newNumber(1)
+	case lok && ln.Val == 0 && rok && rn.Val > 0:
+		return newNumber(0)
+	}
+	// (x^a)^n => x^(a*n)，仅在n为整数时代数成立，浮点计算结果可能不同
+	if node, ok := l.(OperatorExprNode); ok && node.Op == "^" && rok && s.opts.Collect && rn.Val == math.Trunc(rn.Val) {
+		return s.power(node.Lhs, s.product(newBinary("*", node.Rhs, rn)))
+	}
+	return newBinary("^", l, r)
+}
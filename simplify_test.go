@@ -0,0 +1,146 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"x + 0", "x"},
+		{"0 - x", "-x"},
+		{"1 * x", "x"},
+		{"x * 0", "0"},
+		{"x ^ 1", "x"},
+		{"x ^ 0", "1"},
+		{"x - x", "0"},
+		{"-(-x)", "x"},
+		{"-(2*x)", "-2 * x"},
+		{"x + -y", "x - y"},
+		{"x - -2", "x + 2"},
+		{"-1 * x * 1", "-x"},
+		{"0.5 + 0.25 + y", "0.75 + y"},
+		{"2*3*x", "6 * x"},
+		{"2^10", "1024"},
+		{"2^-2", "0.25"},
+		{"1 > 0 ? x : y", "x"},
+		// 默认不合并同类项及同底数幂
+		{"2*x + 3*x", "2 * x + 3 * x"},
+		{"x + a - a", "x + a - a"},
+		{"(x^2)^3", "(x^2)^3"},
+	}
+	for _, tt := range tests {
+		if got := Format(Simplify(mustParse(t, tt.src), nil)); got != tt.want {
+			t.Errorf("Simplify(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSimplifyCollect(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"2*x + 3*x", "5 * x"},
+		{"x*x*x", "x^3"},
+		{"(6*x)/3", "2 * x"},
+		{"x - x", "0"},
+		{"-(-x)", "x"},
+		{"0.5 + y + 0.25", "y + 0.75"},
+		{"(x^2)^3", "x^6"},
+	}
+	for _, tt := range tests {
+		if got := Format(Simplify(mustParse(t, tt.src), &SimplifyOptions{Collect: true})); got != tt.want {
+			t.Errorf("Simplify(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSimplifyInexact(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"0.1 + 0.2 + y", "y + 0.1 + 0.2"},
+		{"0.1*x + 0.2*x", "0.1 * x + 0.2 * x"},
+		{"1/3", "1 / 3"},
+		{"2^0.5", "2^0.5"},
+	}
+	for _, tt := range tests {
+		if got := Format(Simplify(mustParse(t, tt.src), &SimplifyOptions{Collect: true})); got != tt.want {
+			t.Errorf("Simplify(%s) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSimplifyContext(t *testing.T) {
+	opts := &SimplifyOptions{FoldFuncs: true}
+	deg := WithOptions(context.Background(), Options{AngleUnit: Degree})
+	if got := Format(SimplifyContext(deg, mustParse(t, "sin(90) * x"), opts)); got != "x" {
+		t.Errorf("sin(90) * x in degree = %s", got)
+	}
+	if got := Format(Simplify(mustParse(t, "sin(0) + x"), opts)); got != "x" {
+		t.Errorf("sin(0) + x = %s", got)
+	}
+	dec := WithOptions(context.Background(), Options{Precision: PrecisionDecimal})
+	if got := Format(SimplifyContext(dec, mustParse(t, "sqrt(2) + 0.5*2"), opts)); got != "sqrt(2) + 1" {
+		t.Errorf("decimal mode = %s", got)
+	}
+}
+
+func TestSimplifyPreservesValue(t *testing.T) {
+	ctx := varsCtx(map[string]any{"x": 1.5, "y": -2, "a": 1e16})
+	for _, src := range []string{
+		"2*x + 3*y - x", "x*x/x + y^2*y", "(x+1)*(x+1) - x^2", "3*(x - y)/3",
+		"x + a - a", "a + x - a", "(x^0.5)^2", "0.1*x + 0.2*x", "-(0.1*x) * -1 + 0*y",
+	} {
+		expr := mustParse(t, src)
+		want, err := Evaluate(ctx, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Evaluate(ctx, Simplify(expr, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if got != want {
+			t.Errorf("%s: simplified %s = %v, want %v", src, Format(Simplify(expr, nil)), got, want)
+		}
+	}
+}
+
+// 合并同类项仅保证代数等价，以下重写会改变浮点计算结果
+func TestSimplifyAlgebraic(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		vars map[string]any
+	}{
+		{"a + 1e16 - 1e16", "a", map[string]any{"a": 1}},
+		{"x + y - x", "y", map[string]any{"x": 1e16, "y": 1}},
+		{"(x^0.5)^2", "x", map[string]any{"x": 2}},
+	}
+	for _, tt := range tests {
+		expr := mustParse(t, tt.src)
+		simplified := Simplify(expr, &SimplifyOptions{Collect: true})
+		if got := Format(simplified); got != tt.want {
+			t.Errorf("Simplify(%s) = %s, want %s", tt.src, got, tt.want)
+			continue
+		}
+		ctx := varsCtx(tt.vars)
+		orig, err := Evaluate(ctx, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Evaluate(ctx, simplified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orig == got {
+			t.Errorf("%s: expected rounding difference, both evaluate to %v", tt.src, got)
+		}
+	}
+}
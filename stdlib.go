@@ -0,0 +1,553 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strings"
+	"unicode/utf8"
+)
+
+// stdFunc 标准库函数
+type stdFunc struct {
+	name  string
+	min   int // 最少参数个数
+	max   int // 最多参数个数，-1表示不限
+	fn    func(args []float64) (float64, error)
+	latex func(args []string) string
+	angle angleKind
+	base  bool // 首个参数为幂的底数，latex中非原子参数按^的规则加括号
+
+	rat      func(args []*big.Rat) (*big.Rat, error)     // 有理数实现，为空时PrecisionRat模式返回ErrInexact
+	bigFloat func(args []*big.Float) (*big.Float, error) // big.Float实现
+	decimal  func(args []Decimal, policy DecimalPolicy) (Decimal, error)
+	complex  func(args []complex128) (complex128, error) // 复数实现，为空时仅支持实数参数
+	interval func(args []Interval) (Interval, error)     // 区间实现，为空时仅支持单点区间
+}
+
+// angleKind 参数或结果受Options.AngleUnit影响的三角函数
+type angleKind int
+
+const (
+	angleNone   angleKind = iota
+	angleArg              // 参数为角度
+	angleResult           // 结果为角度
+)
+
+func (f *stdFunc) Argc() int {
+	if f.min == f.max {
+		return f.min
+	}
+	return -1
+}
+
+func (f *stdFunc) checkArgc(n int) error {
+	if n < f.min || (f.max >= 0 && n > f.max) {
+		if f.max < 0 {
+			return errors.New(fmt.Sprintf("parameters want at least %d but get %d", f.min, n))
+		}
+		return errors.New(fmt.Sprintf("parameters want %d to %d but get %d", f.min, f.max, n))
+	}
+	return nil
+}
+
+func (f *stdFunc) Evaluate(ctx context.Context, args ...ExprNode) (float64, error) {
+	if err := f.checkArgc(len(args)); err != nil {
+		return 0, err
+	}
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		v, err := Evaluate(ctx, arg)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = v
+	}
+	unit := OptionsFrom(ctx).AngleUnit
+	if f.angle == angleArg {
+		for i := range values {
+			values[i] = unit.ToRadian(values[i])
+		}
+	}
+	v, err := f.fn(values)
+	if err == nil && f.angle == angleResult {
+		v = unit.FromRadian(v)
+	}
+	return v, err
+}
+
+// CallFloat 不读取计算选项，三角函数按弧度计算
+func (f *stdFunc) CallFloat(args []float64) (float64, error) {
+	if err := f.checkArgc(len(args)); err != nil {
+		return 0, err
+	}
+	return f.fn(args)
+}
+
+func (f *stdFunc) Calculate(ctx context.Context, args ...ExprNode) float64 {
+	v, err := f.Evaluate(ctx, args...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (f *stdFunc) ToExprStr(ctx context.Context, args ...ExprNode) string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = ToExprStr(arg, ctx)
+	}
+	return f.name + "(" + strings.Join(strs, ", ") + ")"
+}
+
+func (f *stdFunc) LaTex(ctx context.Context, args ...ExprNode) string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = ToLaTex(arg, ctx)
+	}
+	if f.base && len(args) > 0 && latexNeedParens(EnvironmentFrom(ctx), "^", args[0], false) {
+		strs[0] = latexParens(strs[0])
+	}
+	if f.latex != nil {
+		return f.latex(strs)
+	}
+	return "\\operatorname{" + f.name + "}\\left(" + strings.Join(strs, ", ") + "\\right)"
+}
+
+// stdDiffFunc 可求导的标准库函数
+type stdDiffFunc struct {
+	*stdFunc
+	diff    func(args []ExprNode) ExprNode
+	partial func(args []ExprNode, i int) ExprNode // 关于首个参数以外的参数的偏导数，为空时不可求导
+}
+
+func (f *stdDiffFunc) DiffExprNode(ctx context.Context, args ...ExprNode) ExprNode {
+	return f.diff(args)
+}
+
+func (f *stdDiffFunc) PartialDiffExprNode(ctx context.Context, i int, args ...ExprNode) ExprNode {
+	if i == 0 {
+		return f.diff(args)
+	}
+	if f.partial == nil || i >= len(args) {
+		return nil
+	}
+	return f.partial(args, i)
+}
+
+// 标准库函数定义
+var stdFuncs = []DefFunc{
+	// 三角函数
+	withInterval(intervalSin, withAngle(angleArg, withComplex(complexUnary(cmplx.Sin), unary("sin", math.Sin, latexCmd("\\sin"), func(u ExprNode) ExprNode {
+		return newCall("cos", u)
+	})))),
+	withInterval(intervalCos, withAngle(angleArg, withComplex(complexUnary(cmplx.Cos), unary("cos", math.Cos, latexCmd("\\cos"), func(u ExprNode) ExprNode {
+		return newNeg(newCall("sin", u))
+	})))),
+	withAngle(angleArg, withComplex(complexUnary(cmplx.Tan), unary("tan", math.Tan, latexCmd("\\tan"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("^", newCall("cos", u), newNumber(2)))
+	}))),
+	withInterval(intervalDomain(intervalMonotone(math.Asin, true, -2, 2), -1, 1), withAngle(angleResult, withComplex(complexUnary(cmplx.Asin), unary("asin", math.Asin, latexCmd("\\arcsin"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newCall("sqrt", newBinary("-", newNumber(1), newBinary("^", u, newNumber(2)))))
+	})))),
+	withInterval(intervalDomain(intervalMonotone(math.Acos, false, 0, 4), -1, 1), withAngle(angleResult, withComplex(complexUnary(cmplx.Acos), unary("acos", math.Acos, latexCmd("\\arccos"), func(u ExprNode) ExprNode {
+		return newNeg(newBinary("/", newNumber(1), newCall("sqrt", newBinary("-", newNumber(1), newBinary("^", u, newNumber(2))))))
+	})))),
+	withInterval(intervalMonotone(math.Atan, true, -2, 2), withAngle(angleResult, withComplex(complexUnary(cmplx.Atan), unary("atan", math.Atan, latexCmd("\\arctan"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("+", newNumber(1), newBinary("^", u, newNumber(2))))
+	})))),
+	&stdFunc{name: "atan2", min: 2, max: 2, fn: func(args []float64) (float64, error) {
+		return math.Atan2(args[0], args[1]), nil
+	}, angle: angleResult},
+
+	// 双曲函数
+	withInterval(intervalMonotone(math.Sinh, true, math.Inf(-1), math.Inf(1)), withComplex(complexUnary(cmplx.Sinh), unary("sinh", math.Sinh, latexCmd("\\sinh"), func(u ExprNode) ExprNode {
+		return newCall("cosh", u)
+	}))),
+	withComplex(complexUnary(cmplx.Cosh), unary("cosh", math.Cosh, latexCmd("\\cosh"), func(u ExprNode) ExprNode {
+		return newCall("sinh", u)
+	})),
+	withInterval(intervalMonotone(math.Tanh, true, -1, 1), withComplex(complexUnary(cmplx.Tanh), unary("tanh", math.Tanh, latexCmd("\\tanh"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("^", newCall("cosh", u), newNumber(2)))
+	}))),
+	withComplex(complexUnary(cmplx.Asinh), unary("asinh", math.Asinh, latexCmd("\\operatorname{arsinh}"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newCall("sqrt", newBinary("+", newBinary("^", u, newNumber(2)), newNumber(1))))
+	})),
+	withComplex(complexUnary(cmplx.Acosh), unary("acosh", math.Acosh, latexCmd("\\operatorname{arcosh}"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newCall("sqrt", newBinary("-", newBinary("^", u, newNumber(2)), newNumber(1))))
+	})),
+	withComplex(complexUnary(cmplx.Atanh), unary("atanh", math.Atanh, latexCmd("\\operatorname{artanh}"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("-", newNumber(1), newBinary("^", u, newNumber(2))))
+	})),
+
+	// 指数、对数
+	withInterval(intervalMonotone(math.Exp, true, 0, math.Inf(1)), withComplex(complexUnary(cmplx.Exp), unary("exp", math.Exp, func(args []string) string {
+		return "e^{" + args[0] + "}"
+	}, func(u ExprNode) ExprNode {
+		return newCall("exp", u)
+	}))),
+	withInterval(intervalDomain(intervalMonotone(math.Log, true, math.Inf(-1), math.Inf(1)), 0, math.Inf(1)), withComplex(complexUnary(cmplx.Log), unary("ln", math.Log, latexCmd("\\ln"), func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), u)
+	}))),
+	withInterval(intervalDomain(intervalMonotone(math.Log2, true, math.Inf(-1), math.Inf(1)), 0, math.Inf(1)), withComplex(complexUnary(complexLog2), unary("log2", math.Log2, func(args []string) string {
+		return "\\log_{2}\\left(" + args[0] + "\\right)"
+	}, func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("*", u, newCall("ln", newNumber(2))))
+	}))),
+	withInterval(intervalDomain(intervalMonotone(math.Log10, true, math.Inf(-1), math.Inf(1)), 0, math.Inf(1)), withComplex(complexUnary(cmplx.Log10), unary("log10", math.Log10, func(args []string) string {
+		return "\\log_{10}\\left(" + args[0] + "\\right)"
+	}, func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("*", u, newCall("ln", newNumber(10))))
+	}))),
+	&stdDiffFunc{
+		stdFunc: &stdFunc{name: "log", min: 1, max: 2, fn: func(args []float64) (float64, error) {
+			if len(args) == 1 {
+				return math.Log(args[0]), nil
+			}
+			return math.Log(args[0]) / math.Log(args[1]), nil
+		}, latex: func(args []string) string {
+			if len(args) == 1 {
+				return "\\log\\left(" + args[0] + "\\right)"
+			}
+			return "\\log_{" + args[1] + "}\\left(" + args[0] + "\\right)"
+		}, complex: complexLogFunc},
+		diff: func(args []ExprNode) ExprNode {
+			if len(args) == 1 {
+				return newBinary("/", newNumber(1), args[0])
+			}
+			return newBinary("/", newNumber(1), newBinary("*", args[0], newCall("ln", args[1])))
+		},
+		partial: func(args []ExprNode, i int) ExprNode {
+			// log(x, b) = ln(x) / ln(b)，关于b的偏导数为 -ln(x) / (b * ln(b)^2)
+			return newNeg(newBinary("/", newCall("ln", args[0]), newBinary("*", args[1], newBinary("^", newCall("ln", args[1]), newNumber(2)))))
+		},
+	},
+	withInterval(intervalDomain(intervalSqrt, 0, math.Inf(1)), withRat(ratSqrt, withBigFloat(bigFloatSqrt, withComplex(complexUnary(cmplx.Sqrt), unary("sqrt", math.Sqrt, func(args []string) string {
+		return "\\sqrt{" + args[0] + "}"
+	}, func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("*", newNumber(2), newCall("sqrt", u)))
+	}))))),
+	unary("cbrt", math.Cbrt, func(args []string) string {
+		return "\\sqrt[3]{" + args[0] + "}"
+	}, func(u ExprNode) ExprNode {
+		return newBinary("/", newNumber(1), newBinary("*", newNumber(3), newBinary("^", newCall("cbrt", u), newNumber(2))))
+	}),
+	&stdDiffFunc{
+		stdFunc: &stdFunc{name: "pow", min: 2, max: 2, fn: func(args []float64) (float64, error) {
+			return math.Pow(args[0], args[1]), nil
+		}, latex: func(args []string) string {
+			return "{" + args[0] + "}^{" + args[1] + "}"
+		}, base: true, rat: ratPowFunc, bigFloat: bigFloatPowFunc, complex: complexPowFunc, interval: intervalPowFunc},
+		diff: func(args []ExprNode) ExprNode {
+			return newBinary("*", args[1], newCall("pow", args[0], newBinary("-", args[1], newNumber(1))))
+		},
+		partial: func(args []ExprNode, i int) ExprNode {
+			return newBinary("*", newCall("pow", args[0], args[1]), newCall("ln", args[0]))
+		},
+	},
+	&stdDiffFunc{
+		stdFunc: &stdFunc{name: "hypot", min: 2, max: 2, fn: func(args []float64) (float64, error) {
+			return math.Hypot(args[0], args[1]), nil
+		}},
+		diff: func(args []ExprNode) ExprNode {
+			return newBinary("/", args[0], newCall("hypot", args[0], args[1]))
+		},
+		partial: func(args []ExprNode, i int) ExprNode {
+			return newBinary("/", args[1], newCall("hypot", args[0], args[1]))
+		},
+	},
+
+	// 取整、符号
+	withInterval(intervalAbs, withComplex(complexAbs, withRat(ratAbs, unary("abs", math.Abs, func(args []string) string {
+		return "\\left|" + args[0] + "\\right|"
+	}, func(u ExprNode) ExprNode {
+		return newCall("sign", u)
+	})))),
+	withRat(ratSign, unary("sign", sign, latexCmd("\\operatorname{sgn}"), nil)),
+	withInterval(intervalExact(math.Floor), withRat(ratFloorFunc, unary("floor", math.Floor, func(args []string) string {
+		return "\\left\\lfloor " + args[0] + " \\right\\rfloor"
+	}, nil))),
+	withInterval(intervalExact(math.Ceil), withRat(ratCeil, unary("ceil", math.Ceil, func(args []string) string {
+		return "\\left\\lceil " + args[0] + " \\right\\rceil"
+	}, nil))),
+	withInterval(intervalExact(math.Trunc), withRat(ratTruncFunc, unary("trunc", math.Trunc, nil, nil))),
+	&stdFunc{name: "round", min: 1, max: 2, decimal: decimalRound, fn: func(args []float64) (float64, error) {
+		if len(args) == 1 {
+			return math.Round(args[0]), nil
+		}
+		return roundDigits(args[0], math.Trunc(args[1])), nil
+	}, rat: ratRoundFunc},
+	&stdFunc{name: "clamp", min: 3, max: 3, fn: func(args []float64) (float64, error) {
+		if args[1] > args[2] {
+			return 0, errors.New(fmt.Sprintf("lower bound %g is greater than upper bound %g", args[1], args[2]))
+		}
+		return math.Max(args[1], math.Min(args[0], args[2])), nil
+	}, rat: ratClamp},
+
+	// 可变参数
+	&stdFunc{name: "min", min: 1, max: -1, fn: func(args []float64) (float64, error) {
+		r := args[0]
+		for _, v := range args[1:] {
+			r = math.Min(r, v)
+		}
+		return r, nil
+	}, latex: latexCmd("\\min"), rat: ratMin, interval: intervalMin},
+	&stdFunc{name: "max", min: 1, max: -1, fn: func(args []float64) (float64, error) {
+		r := args[0]
+		for _, v := range args[1:] {
+			r = math.Max(r, v)
+		}
+		return r, nil
+	}, latex: latexCmd("\\max"), rat: ratMax, interval: intervalMax},
+	&stdFunc{name: "sum", min: 0, max: -1, fn: func(args []float64) (float64, error) {
+		r := 0.0
+		for _, v := range args {
+			r += v
+		}
+		return r, nil
+	}, rat: ratSum},
+	&stdFunc{name: "avg", min: 1, max: -1, fn: func(args []float64) (float64, error) {
+		r := 0.0
+		for _, v := range args {
+			r += v
+		}
+		return r / float64(len(args)), nil
+	}, rat: ratAvg},
+	&stdFunc{name: "gcd", min: 1, max: -1, fn: func(args []float64) (float64, error) {
+		return foldInt(args, func(a, b int64) (int64, error) {
+			return gcd(a, b), nil
+		})
+	}, latex: latexCmd("\\gcd"), rat: ratGcd},
+	&stdFunc{name: "lcm", min: 1, max: -1, fn: func(args []float64) (float64, error) {
+		return foldInt(args, func(a, b int64) (int64, error) {
+			if a == 0 || b == 0 {
+				return 0, nil
+			}
+			q := a / gcd(a, b)
+			if q > math.MaxInt64/b {
+				return 0, errors.New(fmt.Sprintf("lcm of %d and %d overflows int64", a, b))
+			}
+			return q * b, nil
+		})
+	}, rat: ratLcm},
+	// 复数，实数参数时re、conj返回参数本身，im返回0
+	withComplex(complexRe, unary("re", func(x float64) float64 {
+		return x
+	}, latexCmd("\\operatorname{Re}"), nil)),
+	withComplex(complexIm, unary("im", func(x float64) float64 {
+		return 0
+	}, latexCmd("\\operatorname{Im}"), nil)),
+	withAngle(angleResult, withComplex(complexArg, unary("arg", func(x float64) float64 {
+		return math.Atan2(0, x)
+	}, latexCmd("\\arg"), nil))),
+	withComplex(complexUnary(cmplx.Conj), unary("conj", func(x float64) float64 {
+		return x
+	}, func(args []string) string {
+		return "\\overline{" + args[0] + "}"
+	}, nil)),
+
+	// 字符串函数，在CalculateValue中使用
+	&valueFunc{stdFunc: &stdFunc{name: "concat", min: 0, max: -1}, value: func(args []Value) (Value, error) {
+		var b strings.Builder
+		for _, a := range args {
+			if !a.IsNil() {
+				b.WriteString(a.String())
+			}
+		}
+		return StringValue(b.String()), nil
+	}},
+	&valueFunc{stdFunc: &stdFunc{name: "len", min: 1, max: 1}, value: func(args []Value) (Value, error) {
+		s, err := stringArg("len", args, 0)
+		if err != nil {
+			return Value{}, err
+		}
+		return IntValue(int64(utf8.RuneCountInString(s))), nil
+	}},
+	&valueFunc{stdFunc: &stdFunc{name: "upper", min: 1, max: 1}, value: func(args []Value) (Value, error) {
+		s, err := stringArg("upper", args, 0)
+		if err != nil {
+			return Value{}, err
+		}
+		return StringValue(strings.ToUpper(s)), nil
+	}},
+	&valueFunc{stdFunc: &stdFunc{name: "contains", min: 2, max: 2}, value: func(args []Value) (Value, error) {
+		s, err := stringArg("contains", args, 0)
+		if err != nil {
+			return Value{}, err
+		}
+		sub, err := stringArg("contains", args, 1)
+		if err != nil {
+			return Value{}, err
+		}
+		return BoolValue(strings.Contains(s, sub)), nil
+	}},
+}
+
+// unary 创建单参数标准库函数，diff为空时不支持求导
+func unary(name string, fn func(float64) float64, latex func(args []string) string, diff func(u ExprNode) ExprNode) DefFunc {
+	f := &stdFunc{name: name, min: 1, max: 1, fn: func(args []float64) (float64, error) {
+		return fn(args[0]), nil
+	}, latex: latex}
+	if diff == nil {
+		return f
+	}
+	return &stdDiffFunc{stdFunc: f, diff: func(args []ExprNode) ExprNode {
+		return diff(args[0])
+	}}
+}
+
+// withAngle 标记三角函数的角度单位
+func withAngle(kind angleKind, f DefFunc) DefFunc {
+	switch t := f.(type) {
+	case *stdFunc:
+		t.angle = kind
+	case *stdDiffFunc:
+		t.angle = kind
+	}
+	return f
+}
+
+// isAngleFunc 判断函数是否受Options.AngleUnit影响
+func isAngleFunc(f DefFunc) bool {
+	return funcAngle(f) != angleNone
+}
+
+// funcAngle 标准库三角函数的角度类型
+func funcAngle(f DefFunc) angleKind {
+	switch t := f.(type) {
+	case *stdFunc:
+		return t.angle
+	case *stdDiffFunc:
+		return t.angle
+	}
+	return angleNone
+}
+
+// latexCmd 以latex命令输出函数
+func latexCmd(cmd string) func(args []string) string {
+	return func(args []string) string {
+		return cmd + "\\left(" + strings.Join(args, ", ") + "\\right)"
+	}
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return x
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// roundDigits 四舍五入保留d位小数，d为负时舍入到整数位
+// 10^d溢出或x在该位数下已是整数（超出float64精度）时返回x，|x|小于10^-d的一半时返回0
+func roundDigits(x float64, d float64) float64 {
+	if d >= 0 {
+		p := math.Pow(10, d)
+		if math.IsInf(p, 0) || math.Abs(x*p) >= 1<<52 {
+			return x
+		}
+		return math.Round(x*p) / p
+	}
+	q := math.Pow(10, -d)
+	if math.IsInf(q, 0) {
+		return math.Copysign(0, x)
+	}
+	return math.Round(x/q) * q
+}
+
+// foldInt 对整数参数的绝对值依次执行fn
+func foldInt(args []float64, fn func(a, b int64) (int64, error)) (float64, error) {
+	ints := make([]int64, len(args))
+	for i, v := range args {
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, errors.New(fmt.Sprintf("integer parameter required but get %g", v))
+		}
+		// int64转换仅在 |v| < 2^63 时有定义
+		if math.Abs(v) >= 1<<63 {
+			return 0, errors.New(fmt.Sprintf("integer parameter %g is out of range", v))
+		}
+		ints[i] = int64(math.Abs(v))
+	}
+	r := ints[0]
+	for _, v := range ints[1:] {
+		var err error
+		if r, err = fn(r, v); err != nil {
+			return 0, err
+		}
+	}
+	return float64(r), nil
+}
+
+// StdFuncNames 标准库函数名称
+func StdFuncNames() []string {
+	names := make([]string, len(stdFuncs))
+	for i, f := range stdFuncs {
+		names[i] = stdFuncName(f)
+	}
+	return names
+}
+
+func stdFuncName(f DefFunc) string {
+	switch t := f.(type) {
+	case *stdFunc:
+		return t.name
+	case *stdDiffFunc:
+		return t.name
+	case *valueFunc:
+		return t.name
+	}
+	return ""
+}
+
+// isStdFunc 判断是否为标准库函数，可被同名自定义函数覆盖
+func isStdFunc(f DefFunc) bool {
+	return stdFuncName(f) != ""
+}
+
+// RegStdFuncs 注册标准库函数，names为空时注册全部
+func (e *Environment) RegStdFuncs(names ...string) error {
+	for _, f := range stdFuncs {
+		name := stdFuncName(f)
+		if len(names) > 0 && !containsStr(names, name) {
+			continue
+		}
+		if err := e.RegDefFunc(name, f); err != nil {
+			return errors.New(fmt.Sprintf("%v: %s", err, name))
+		}
+	}
+	return nil
+}
+
+// UnregStdFuncs 移除标准库函数，names为空时移除全部，同名的自定义函数保持不变
+func (e *Environment) UnregStdFuncs(names ...string) {
+	for _, f := range stdFuncs {
+		name := stdFuncName(f)
+		if len(names) > 0 && !containsStr(names, name) {
+			continue
+		}
+		if def, ok := e.Func(name); ok && isStdFunc(def) {
+			e.UnregDefFunc(name)
+		}
+	}
+}
+
+func containsStr(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestStdFuncs(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"sin(pi/2)", 1},
+		{"cos(0)", 1},
+		{"atan2(1, 1)", math.Pi / 4},
+		{"exp(1)", math.E},
+		{"ln(e)", 1},
+		{"log2(8)", 3},
+		{"log10(1000)", 3},
+		{"log(8, 2)", 3},
+		{"sqrt(16)", 4},
+		{"cbrt(27)", 3},
+		{"pow(2, 10)", 1024},
+		{"hypot(3, 4)", 5},
+		{"abs(-2)", 2},
+		{"sign(-3)", -1},
+		{"floor(-1.5)", -2},
+		{"ceil(1.2)", 2},
+		{"trunc(-1.7)", -1},
+		{"round(2.5)", 3},
+		{"round(-2.5)", -3},
+		{"round(1234.5678, 2)", 1234.57},
+		{"round(1250, -2)", 1300},
+		{"round(1.5, 400)", 1.5},
+		{"round(0.1, 20)", 0.1},
+		{"round(1e300, 10)", 1e300},
+		{"round(1e300, -400)", 0},
+		{"round(-1250, -2)", -1300},
+		{"clamp(5, 0, 3)", 3},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"sum(1, 2, 3)", 6},
+		{"avg(1, 2, 3)", 2},
+		{"gcd(12, 18)", 6},
+		{"lcm(4, 6)", 12},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(context.Background(), mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestStdFuncsDegree(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{AngleUnit: Degree})
+	for src, want := range map[string]float64{"sin(30)": 0.5, "cos(60)": 0.5, "asin(1)": 90, "atan2(1, 1)": 45} {
+		got, err := Evaluate(ctx, mustParse(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s in degree = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestStdFuncsErrors(t *testing.T) {
+	for _, src := range []string{"clamp(1, 3, 0)", "min()", "gcd(1e300, 6)", "lcm(-1e19, 4)", "gcd(1.5, 3)", "lcm(4611686018427387904, 3)"} {
+		if _, err := Evaluate(context.Background(), mustParse(t, src)); err == nil {
+			t.Errorf("%s: want error", src)
+		}
+	}
+	p, err := Compile(mustParse(t, "lcm(4611686018427387904, 3)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := p.Eval(nil); err == nil {
+		t.Errorf("compiled lcm overflow = %v, want error", v)
+	}
+	_, err = Evaluate(context.Background(), mustParse(t, "sqrt(-1)"))
+	if err != nil {
+		t.Errorf("sqrt(-1) without Strict: %v", err)
+	}
+	_, err = Evaluate(WithOptions(context.Background(), Options{Strict: true}), mustParse(t, "sqrt(-1)"))
+	if !errors.Is(err, ErrNotFinite) {
+		t.Errorf("sqrt(-1) with Strict: %v", err)
+	}
+}
+
+func TestUnregStdFuncs(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.UnregStdFuncs()
+	if _, ok := env.Func("sin"); ok {
+		t.Error("sin is registered after UnregStdFuncs")
+	}
+	if _, ok := DefaultEnvironment().Func("sin"); !ok {
+		t.Error("UnregStdFuncs changed the default environment")
+	}
+	custom := NewEnvironment(nil)
+	if err := custom.RegDefFunc("sin", &countFunc{}); err != nil {
+		t.Fatal(err)
+	}
+	custom.UnregStdFuncs()
+	if f, ok := custom.Func("sin"); !ok || isStdFunc(f) {
+		t.Error("UnregStdFuncs removed the user-defined sin")
+	}
+	if _, ok := custom.Func("cos"); ok {
+		t.Error("cos is registered after UnregStdFuncs")
+	}
+}
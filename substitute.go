@@ -0,0 +1,141 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Substitute 将变量替换为对应的表达式，生成新的表达式，原表达式不会被修改
+// 替换只进行一次，替换后的表达式中的变量不会再次替换
+func Substitute(expr ExprNode, vars map[string]ExprNode) ExprNode {
+	return Rewrite(expr, func(node ExprNode) (ExprNode, bool) {
+		if v, ok := node.(VariableExprNode); ok {
+			if r, ok := vars[v.Val]; ok && r != nil {
+				return r, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// PartialEvaluate 代入已知变量并折叠结果为常量的子树，其余部分保持符号形式
+// known 的值为数值或字符串表达式，字符串表达式中的变量不再代入
+// 不含变量的子树按Calculate的结果折叠为数值，如 x + 0.1 代入 x = 0.2 得到 0.30000000000000004，函数调用按弧度折叠；
+// 含变量的部分仅应用 x + 0、x * 1、x ^ 1 等精确的恒等规则，不重组运算顺序，结果与代入变量后的原表达式计算结果一致
+func PartialEvaluate(expr ExprNode, known map[string]any) (ExprNode, error) {
+	return defaultEnv.PartialEvaluate(expr, known)
+}
+
+// PartialEvaluateContext 使用上下文中的运行环境及计算选项部分计算表达式，函数调用按Options.AngleUnit折叠
+// 非float64精度模式下折叠结果无法以float64保持精度，仅折叠结果可精确表示的内置四则运算及整数次幂
+func PartialEvaluateContext(ctx context.Context, expr ExprNode, known map[string]any) (ExprNode, error) {
+	return EnvironmentFrom(ctx).partialEvaluate(ctx, expr, known)
+}
+
+// PartialEvaluate 在当前环境中部分计算表达式
+func (e *Environment) PartialEvaluate(expr ExprNode, known map[string]any) (ExprNode, error) {
+	return e.partialEvaluate(context.Background(), expr, known)
+}
+
+func (e *Environment) partialEvaluate(ctx context.Context, expr ExprNode, known map[string]any) (ExprNode, error) {
+	vars := make(map[string]ExprNode, len(known))
+	for name, value := range known {
+		if s, ok := value.(string); ok {
+			node, err := e.ParseExpression(s)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("variable `%s`: %v", name, err))
+			}
+			vars[name] = node
+			continue
+		}
+		f, ok := toFloat64(value)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("variable `%s` type %T is not supported", name, value))
+		}
+		vars[name] = newNumber(f)
+	}
+	ctx = WithEnvironment(ctx, e)
+	float := OptionsFrom(ctx).Precision == PrecisionFloat64
+	return Rewrite(Substitute(expr, vars), func(node ExprNode) (ExprNode, bool) {
+		if r, ok := foldConstSubtree(ctx, node, float); ok {
+			return r, true
+		}
+		return exactIdentity(e, node)
+	}), nil
+}
+
+// foldConstSubtree 将子节点均为数值的节点计算为数值，计算失败时保留原节点
+// 非float64精度模式下仅折叠结果精确的内置四则运算及整数次幂
+func foldConstSubtree(ctx context.Context, node ExprNode, float bool) (ExprNode, bool) {
+	switch n := node.(type) {
+	case OperatorExprNode:
+		if !float && !isBuiltinOperator(EnvironmentFrom(ctx), n.Op) {
+			return nil, false
+		}
+	case UnaryExprNode, FunCallerExprNode, ConditionalExprNode, ConstExprNode:
+		if !float {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	for _, child := range Children(node) {
+		if _, ok := child.(NumberExprNode); !ok {
+			return nil, false
+		}
+	}
+	v, err := Evaluate(ctx, node)
+	if err != nil {
+		return nil, false
+	}
+	if !float {
+		n := node.(OperatorExprNode)
+		if !exactResult(n.Op, n.Lhs.(NumberExprNode).Val, n.Rhs.(NumberExprNode).Val, v) {
+			return nil, false
+		}
+	}
+	return newNumber(v), true
+}
+
+// exactIdentity 应用不改变计算结果的恒等规则：x + 0、0 + x、x - 0、x * 1、1 * x、x / 1、x ^ 1，及条件为数值的条件表达式
+func exactIdentity(env *Environment, node ExprNode) (ExprNode, bool) {
+	switch n := node.(type) {
+	case ConditionalExprNode:
+		if c, ok := n.Cond.(NumberExprNode); ok {
+			if c.Val != 0 {
+				return n.Then, true
+			}
+			return n.Else, true
+		}
+	case OperatorExprNode:
+		if !isBuiltinOperator(env, n.Op) || isPrefixOperand(n.Lhs) {
+			return nil, false
+		}
+		switch n.Op {
+		case "+":
+			if isZeroNode(n.Rhs) {
+				return n.Lhs, true
+			}
+			if isZeroNode(n.Lhs) {
+				return n.Rhs, true
+			}
+		case "-":
+			if isZeroNode(n.Rhs) {
+				return n.Lhs, true
+			}
+		case "*":
+			if isOneNode(n.Rhs) {
+				return n.Lhs, true
+			}
+			if isOneNode(n.Lhs) {
+				return n.Rhs, true
+			}
+		case "/", "^":
+			if isOneNode(n.Rhs) {
+				return n.Lhs, true
+			}
+		}
+	}
+	return nil, false
+}
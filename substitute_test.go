@@ -0,0 +1,100 @@
+package mathastc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubstitute(t *testing.T) {
+	expr := mustParse(t, "x * y + x")
+	got := Substitute(expr, map[string]ExprNode{"x": mustParse(t, "a + 1"), "a": mustParse(t, "2")})
+	if s := Format(got); s != "(a + 1) * y + (a + 1)" {
+		t.Errorf("Substitute = %s", s)
+	}
+	if Format(expr) != "x * y + x" {
+		t.Errorf("Substitute modified the original expression: %s", Format(expr))
+	}
+}
+
+func TestPartialEvaluate(t *testing.T) {
+	tests := []struct {
+		src   string
+		known map[string]any
+		want  string
+	}{
+		{"x * y + z", map[string]any{"x": 2, "y": 3}, "6 + z"},
+		{"x + 0.1", map[string]any{"x": 0.2}, "0.30000000000000004"},
+		{"sin(x) + 0.1 + x*y", map[string]any{"x": 1}, "0.9414709848078965 + y"},
+		{"x + 0.25", map[string]any{"x": 0.5}, "0.75"},
+		{"x + y", map[string]any{"y": "2*z"}, "x + 2 * z"},
+		{"sin(x) + y", map[string]any{"x": 0}, "y"},
+		{"x * 1 + (k > 0 ? y : z)", map[string]any{"k": 1}, "x + y"},
+		{"x + a - a", map[string]any{"a": 1e16}, "x + 10000000000000000 - 10000000000000000"},
+		{"2*x + 3*x", map[string]any{"y": 1}, "2 * x + 3 * x"},
+	}
+	for _, tt := range tests {
+		got, err := PartialEvaluate(mustParse(t, tt.src), tt.known)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if s := Format(got); s != tt.want {
+			t.Errorf("PartialEvaluate(%s) = %s, want %s", tt.src, s, tt.want)
+		}
+	}
+	if _, err := PartialEvaluate(mustParse(t, "x"), map[string]any{"x": []int{1}}); err == nil {
+		t.Error("want error for unsupported variable type")
+	}
+}
+
+func TestPartialEvaluateContext(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{AngleUnit: Degree})
+	got, err := PartialEvaluateContext(ctx, mustParse(t, "sin(x) * y"), map[string]any{"x": 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Format(got); s != "y" {
+		t.Errorf("sin(90) * y in degree = %s", s)
+	}
+}
+
+func TestPartialEvaluatePreservesValue(t *testing.T) {
+	ctx := varsCtx(map[string]any{"x": 1, "a": 1e16, "b": 0.1})
+	for _, src := range []string{"x + a - a", "a + x - a", "x * b + b * x", "(x + b) * 1 + 0"} {
+		expr := mustParse(t, src)
+		want, err := Evaluate(ctx, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reduced, err := PartialEvaluate(expr, map[string]any{"a": 1e16, "b": 0.1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := Evaluate(ctx, reduced); err != nil || got != want {
+			t.Errorf("%s: reduced %s = %v, %v, want %v", src, Format(reduced), got, err, want)
+		}
+	}
+}
+
+func TestPartialEvaluateRat(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options{Precision: PrecisionRat})
+	tests := []struct {
+		src   string
+		known map[string]any
+		want  string
+	}{
+		{"x * 2 + y", map[string]any{"x": 0.5}, "1 + y"},
+		{"x * 0.1 + y", map[string]any{"x": 3}, "3 * 0.1 + y"},
+		{"sqrt(x) * y", map[string]any{"x": 4}, "sqrt(4) * y"},
+	}
+	for _, tt := range tests {
+		got, err := PartialEvaluateContext(ctx, mustParse(t, tt.src), tt.known)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if s := Format(got); s != tt.want {
+			t.Errorf("PartialEvaluateContext(%s) = %s, want %s", tt.src, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+)
+
+// Symbol 表达式引用的变量、函数或常量，Spans为在源码中出现的位置
+type Symbol struct {
+	Name  string
+	Spans []Span
+}
+
+// symbolSet 按首次出现顺序去重的名称集合
+type symbolSet struct {
+	symbols []Symbol
+	index   map[string]int
+}
+
+func (s *symbolSet) add(name string, span Span) {
+	if s.index == nil {
+		s.index = map[string]int{}
+	}
+	i, ok := s.index[name]
+	if !ok {
+		i = len(s.symbols)
+		s.index[name] = i
+		s.symbols = append(s.symbols, Symbol{Name: name})
+	}
+	if span.IsValid() {
+		s.symbols[i].Spans = append(s.symbols[i].Spans, span)
+	}
+}
+
+// Variables 表达式引用的变量，按首次出现顺序去重
+func Variables(expr ExprNode) []Symbol {
+	var set symbolSet
+	Inspect(expr, func(node ExprNode) bool {
+		if v, ok := node.(VariableExprNode); ok {
+			set.add(v.Val, v.Span)
+		}
+		return true
+	})
+	return set.symbols
+}
+
+// Functions 表达式调用的函数，按首次出现顺序去重
+func Functions(expr ExprNode) []Symbol {
+	var set symbolSet
+	Inspect(expr, func(node ExprNode) bool {
+		if f, ok := node.(FunCallerExprNode); ok {
+			set.add(f.Name, f.Span)
+		}
+		return true
+	})
+	return set.symbols
+}
+
+// Constants 表达式引用的常量，按首次出现顺序去重
+func Constants(expr ExprNode) []Symbol {
+	var set symbolSet
+	Inspect(expr, func(node ExprNode) bool {
+		if c, ok := node.(ConstExprNode); ok {
+			set.add(c.Name, c.Span)
+		}
+		return true
+	})
+	return set.symbols
+}
+
+// TransitiveVariables 表达式直接及间接引用的变量，沿变量值为字符串或ExprNode的公式展开
+// 变量值从ctx中的Options.Resolver或Parameter获取，未绑定的变量同样返回，其他解析错误直接返回；
+// 间接引用的变量不记录位置，循环引用时返回CycleError
+func TransitiveVariables(ctx context.Context, expr ExprNode) ([]Symbol, error) {
+	env := EnvironmentFrom(ctx)
+	opts := OptionsFrom(ctx)
+	var set symbolSet
+	var expanding []string
+	expanded := map[string]bool{}
+
+	var visit func(expr ExprNode, direct bool) error
+	visit = func(expr ExprNode, direct bool) error {
+		for _, v := range Variables(expr) {
+			if direct {
+				for _, span := range v.Spans {
+					set.add(v.Name, span)
+				}
+			} else {
+				set.add(v.Name, Span{})
+			}
+			if expanded[v.Name] {
+				continue
+			}
+			for i, name := range expanding {
+				if name == v.Name {
+					return &CycleError{Chain: append(append([]string{}, expanding[i:]...), v.Name)}
+				}
+			}
+			value, err := resolveVariable(ctx, opts, v.Name)
+			if err != nil {
+				var unbound *UnboundVariableError
+				if errors.As(err, &unbound) || errors.Is(err, ErrNoParameter) {
+					continue
+				}
+				return err
+			}
+			var sub ExprNode
+			switch t := value.(type) {
+			case string:
+				parameter, _ := GetCtxParameter(ctx)
+				sub, err = parameter.parseVar(env, v.Name, t)
+				if err != nil {
+					return &VariableError{Name: v.Name, Value: t, Err: err}
+				}
+			case ExprNode:
+				sub = t
+			default:
+				continue
+			}
+			expanding = append(expanding, v.Name)
+			err = visit(sub, false)
+			expanding = expanding[:len(expanding)-1]
+			if err != nil {
+				return err
+			}
+			expanded[v.Name] = true
+		}
+		return nil
+	}
+	if err := visit(expr, true); err != nil {
+		return nil, err
+	}
+	return set.symbols, nil
+}
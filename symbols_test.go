@@ -0,0 +1,68 @@
+package mathastc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func symbolNames(symbols []Symbol) []string {
+	names := make([]string, len(symbols))
+	for i, s := range symbols {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestSymbols(t *testing.T) {
+	expr := mustParse(t, "x * sin(y) + x / pi - max(z, e)")
+	if got := symbolNames(Variables(expr)); !equalStrings(got, []string{"x", "y", "z"}) {
+		t.Errorf("Variables = %v", got)
+	}
+	if got := symbolNames(Functions(expr)); !equalStrings(got, []string{"sin", "max"}) {
+		t.Errorf("Functions = %v", got)
+	}
+	if got := symbolNames(Constants(expr)); !equalStrings(got, []string{"pi", "e"}) {
+		t.Errorf("Constants = %v", got)
+	}
+	if spans := Variables(expr)[0].Spans; len(spans) != 2 || spans[0] != (Span{0, 1}) || spans[1] != (Span{13, 14}) {
+		t.Errorf("x spans = %v", spans)
+	}
+}
+
+func TestTransitiveVariables(t *testing.T) {
+	ctx := varsCtx(map[string]any{"a": "b + c", "b": "c * d", "c": 1})
+	got, err := TransitiveVariables(ctx, mustParse(t, "a + f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := symbolNames(got); !equalStrings(names, []string{"a", "b", "c", "d", "f"}) {
+		t.Errorf("TransitiveVariables = %v", names)
+	}
+
+	ctx = varsCtx(map[string]any{"a": "b", "b": "a + 1"})
+	_, err = TransitiveVariables(ctx, mustParse(t, "a"))
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Errorf("cycle: %v", err)
+	}
+
+	ctx = WithOptions(context.Background(), Options{Resolver: MapResolver{"a": "b * 2"}})
+	got, err = TransitiveVariables(ctx, mustParse(t, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := symbolNames(got); !equalStrings(names, []string{"a", "b"}) {
+		t.Errorf("TransitiveVariables with resolver = %v", names)
+	}
+
+	failing := ResolverFunc(func(ctx context.Context, name string) (any, error) {
+		if name == "a" {
+			return "b", nil
+		}
+		return nil, errors.New("resolver failed")
+	})
+	if _, err := TransitiveVariables(WithOptions(context.Background(), Options{Resolver: failing}), mustParse(t, "a")); err == nil || err.Error() != "resolver failed" {
+		t.Errorf("resolver error: %v", err)
+	}
+}
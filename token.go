@@ -3,10 +3,12 @@ package mathastc
 type TokenType int32
 
 const (
-	IdentifierType TokenType = iota // 标识符(常量、函数、变量)
-	LiteralType                     // 字面文字
-	OperatorType                    // 操作符号
-	CommaType                       // 逗号
+	IdentifierType  TokenType = iota // 标识符(常量、函数、变量)
+	LiteralType                      // 字面文字
+	OperatorType                     // 操作符号
+	CommaType                        // 逗号
+	ConditionalType                  // 条件运算符 ? :
+	StringType                       // 字符串字面量，Value为含引号的源码
 )
 
 type Token struct {
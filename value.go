@@ -0,0 +1,569 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ValueKind 值类型
+type ValueKind int
+
+const (
+	NilKind    ValueKind = iota // 空值
+	IntKind                     // int64
+	FloatKind                   // float64
+	BoolKind                    // 布尔值
+	StringKind                  // 字符串
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case IntKind:
+		return "int"
+	case FloatKind:
+		return "float"
+	case BoolKind:
+		return "bool"
+	case StringKind:
+		return "string"
+	}
+	return "nil"
+}
+
+// Value 带类型的计算结果，int64、float64、bool、string或nil，零值为nil
+type Value struct {
+	kind ValueKind
+	i    int64
+	f    float64
+	s    string
+}
+
+// IntValue 创建整数值
+func IntValue(i int64) Value {
+	return Value{kind: IntKind, i: i}
+}
+
+// FloatValue 创建浮点数值
+func FloatValue(f float64) Value {
+	return Value{kind: FloatKind, f: f}
+}
+
+// BoolValue 创建布尔值
+func BoolValue(b bool) Value {
+	if b {
+		return Value{kind: BoolKind, i: 1}
+	}
+	return Value{kind: BoolKind}
+}
+
+// StringValue 创建字符串值
+func StringValue(s string) Value {
+	return Value{kind: StringKind, s: s}
+}
+
+// ValueOf 转换Go类型，支持整数、浮点数、bool、string、nil及Value
+// 超出int64范围的无符号整数及高精度数值转换为float64
+func ValueOf(v any) (Value, bool) {
+	switch t := v.(type) {
+	case nil:
+		return Value{}, true
+	case Value:
+		return t, true
+	case int:
+		return IntValue(int64(t)), true
+	case int8:
+		return IntValue(int64(t)), true
+	case int16:
+		return IntValue(int64(t)), true
+	case int32:
+		return IntValue(int64(t)), true
+	case int64:
+		return IntValue(t), true
+	case uint:
+		return ValueOf(uint64(t))
+	case uint8:
+		return IntValue(int64(t)), true
+	case uint16:
+		return IntValue(int64(t)), true
+	case uint32:
+		return IntValue(int64(t)), true
+	case uint64:
+		if t > math.MaxInt64 {
+			return FloatValue(float64(t)), true
+		}
+		return IntValue(int64(t)), true
+	case float32:
+		return FloatValue(float64(t)), true
+	case float64:
+		return FloatValue(t), true
+	case bool:
+		return BoolValue(t), true
+	case string:
+		return StringValue(t), true
+	case *big.Int:
+		if t.IsInt64() {
+			return IntValue(t.Int64()), true
+		}
+	}
+	if f, ok := toFloat64(v); ok {
+		return FloatValue(f), true
+	}
+	return Value{}, false
+}
+
+// Kind 值类型
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// IsNil 是否为空值
+func (v Value) IsNil() bool {
+	return v.kind == NilKind
+}
+
+// Int 获取整数值
+func (v Value) Int() (int64, bool) {
+	return v.i, v.kind == IntKind
+}
+
+// Float 获取数值，整数转换为float64
+func (v Value) Float() (float64, bool) {
+	switch v.kind {
+	case IntKind:
+		return float64(v.i), true
+	case FloatKind:
+		return v.f, true
+	}
+	return 0, false
+}
+
+// Bool 获取布尔值
+func (v Value) Bool() (bool, bool) {
+	return v.i != 0, v.kind == BoolKind
+}
+
+// Text 获取字符串值
+func (v Value) Text() (string, bool) {
+	return v.s, v.kind == StringKind
+}
+
+// Interface 转换为Go类型 int64、float64、bool、string或nil
+func (v Value) Interface() any {
+	switch v.kind {
+	case IntKind:
+		return v.i
+	case FloatKind:
+		return v.f
+	case BoolKind:
+		return v.i != 0
+	case StringKind:
+		return v.s
+	}
+	return nil
+}
+
+func (v Value) String() string {
+	switch v.kind {
+	case IntKind:
+		return strconv.FormatInt(v.i, 10)
+	case FloatKind:
+		return Float64ToStr(v.f)
+	case BoolKind:
+		return strconv.FormatBool(v.i != 0)
+	case StringKind:
+		return v.s
+	}
+	return "nil"
+}
+
+func (v Value) isNumber() bool {
+	return v.kind == IntKind || v.kind == FloatKind
+}
+
+// ValueOperator 以Value计算的操作符，CalculateValue优先使用，未实现时数值操作数以float64计算
+type ValueOperator interface {
+	ValueResult(a Value, b Value) (Value, error)
+}
+
+// ValueFunc 以Value参数计算的函数，CalculateValue优先使用，未实现时数值参数以float64计算
+type ValueFunc interface {
+	CallValue(args []Value) (Value, error)
+}
+
+// CalculateValue 计算带类型的结果，异常时panic，需要error返回请使用EvaluateValue
+func CalculateValue(expr ExprNode, ctx context.Context) Value {
+	v, err := EvaluateValue(ctx, expr)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// EvaluateValue 计算带类型的结果，整数运算保持int64，溢出或与float64运算时提升为float64
+// 操作数类型不支持时返回TypeError；变量中的字符串作为文本，表达式变量以ExprNode传入
+func EvaluateValue(ctx context.Context, expr ExprNode) (Value, error) {
+	return newNumEvaluator[Value](newEvaluator(ctx), valueArith{}).eval(expr)
+}
+
+// valueArith 带类型的计算
+type valueArith struct{}
+
+func (valueArith) literal(n NumberExprNode) (Value, error) {
+	if n.Str != "" && !strings.ContainsAny(n.Str, ".eE") {
+		if i, err := strconv.ParseInt(n.Str, 10, 64); err == nil {
+			return IntValue(i), nil
+		}
+	}
+	return FloatValue(n.Val), nil
+}
+
+func (valueArith) convert(value any) (Value, bool) {
+	if _, ok := value.(ExprNode); ok {
+		return Value{}, false
+	}
+	return ValueOf(value)
+}
+
+func (valueArith) fromFloat(f float64) (Value, error) {
+	return FloatValue(f), nil
+}
+
+func (valueArith) toFloat(v Value) float64 {
+	if v.kind == BoolKind {
+		return float64(v.i)
+	}
+	f, _ := v.Float()
+	return f
+}
+
+func (valueArith) text(s string) Value {
+	return StringValue(s)
+}
+
+// truth 布尔值或数值，数值非0为真
+func (valueArith) truth(v Value) (bool, error) {
+	switch v.kind {
+	case BoolKind, IntKind:
+		return v.i != 0, nil
+	case FloatKind:
+		return v.f != 0, nil
+	}
+	return false, &TypeError{Want: "bool or number", Kinds: []ValueKind{v.kind}}
+}
+
+func (ar valueArith) shortCircuit(operator OperatorItem, op string, a Value) (Value, bool, error) {
+	switch operator.(type) {
+	case *And, *Or:
+		t, err := ar.truth(a)
+		if err != nil {
+			return Value{}, false, &TypeError{Name: op, Want: "bool or number", Kinds: []ValueKind{a.kind}}
+		}
+		_, isOr := operator.(*Or)
+		if t == isOr {
+			return BoolValue(t), true, nil
+		}
+	default:
+		if s, ok := operator.(ShortCircuitOperator); ok && a.isNumber() {
+			if v, ok := s.ShortCircuit(ar.toFloat(a)); ok {
+				return FloatValue(v), true, nil
+			}
+		}
+	}
+	return Value{}, false, nil
+}
+
+// operator 整数间的加减乘、取模及非负整数次幂保持int64，其他数值运算以float64计算
+func (ar valueArith) operator(operator OperatorItem, op string, a Value, b Value) (Value, bool, error) {
+	if o, ok := operator.(ValueOperator); ok {
+		v, err := o.ValueResult(a, b)
+		return v, true, err
+	}
+	switch operator.(type) {
+	case *Equal:
+		return BoolValue(equalValues(a, b)), true, nil
+	case *NotEqual:
+		return BoolValue(!equalValues(a, b)), true, nil
+	case *Less, *LessEqual, *Greater, *GreaterEqual:
+		cmp, err := compareValues(op, a, b)
+		if err != nil {
+			return Value{}, true, err
+		}
+		r, _ := compareResult(operator, cmp, false, false)
+		return BoolValue(r), true, nil
+	case *And, *Or, *Not:
+		return ar.logic(operator, op, a, b)
+	case *Plus:
+		if a.kind == StringKind && b.kind == StringKind {
+			return StringValue(a.s + b.s), true, nil
+		}
+	}
+	if !a.isNumber() || !b.isNumber() {
+		return Value{}, true, &TypeError{Name: op, Want: "numbers", Kinds: []ValueKind{a.kind, b.kind}}
+	}
+	if a.kind != IntKind || b.kind != IntKind {
+		return Value{}, false, nil
+	}
+	var r int64
+	ok := true
+	switch operator.(type) {
+	case *Plus:
+		r, ok = addInt(a.i, b.i)
+	case *Minus:
+		r, ok = subInt(a.i, b.i)
+	case *Mul:
+		r, ok = mulInt(a.i, b.i)
+	case *Mod:
+		if b.i == 0 {
+			return Value{}, true, &OperatorError{Op: op, Lhs: float64(a.i), Rhs: 0, Err: ErrDivisionByZero}
+		}
+		r = a.i % b.i
+	case *Pow:
+		if b.i < 0 {
+			return Value{}, false, nil
+		}
+		r, ok = powInt(a.i, b.i)
+	default:
+		return Value{}, false, nil
+	}
+	if !ok {
+		// 溢出时提升为float64计算
+		return Value{}, false, nil
+	}
+	return IntValue(r), true, nil
+}
+
+// logic 逻辑运算，操作数为布尔值或数值
+func (ar valueArith) logic(operator OperatorItem, op string, a Value, b Value) (Value, bool, error) {
+	x, errA := ar.truth(a)
+	y, errB := ar.truth(b)
+	if _, ok := operator.(*Not); ok {
+		// 旧版前缀形式仅使用右操作数
+		errA = nil
+	}
+	if errA != nil || errB != nil {
+		return Value{}, true, &TypeError{Name: op, Want: "bool or number", Kinds: []ValueKind{a.kind, b.kind}}
+	}
+	r, _ := compareResult(operator, 0, x, y)
+	return BoolValue(r), true, nil
+}
+
+func (ar valueArith) unary(operator OperatorItem, op string, a Value) (Value, bool, error) {
+	switch operator.(type) {
+	case *Minus:
+		switch a.kind {
+		case IntKind:
+			if a.i == math.MinInt64 {
+				return FloatValue(-float64(a.i)), true, nil
+			}
+			return IntValue(-a.i), true, nil
+		case FloatKind:
+			return FloatValue(-a.f), true, nil
+		}
+	case *Plus:
+		if a.isNumber() {
+			return a, true, nil
+		}
+	case *Not:
+		t, err := ar.truth(a)
+		if err != nil {
+			return Value{}, true, &TypeError{Name: op, Want: "bool or number", Kinds: []ValueKind{a.kind}}
+		}
+		return BoolValue(!t), true, nil
+	default:
+		if a.isNumber() {
+			return Value{}, false, nil
+		}
+	}
+	return Value{}, true, &TypeError{Name: op, Want: "number", Kinds: []ValueKind{a.kind}}
+}
+
+func (ar valueArith) call(def DefFunc, name string, args []Value) (Value, bool, error) {
+	if f, ok := def.(ValueFunc); ok {
+		v, err := f.CallValue(args)
+		return v, true, err
+	}
+	ints := true
+	for _, a := range args {
+		if !a.isNumber() {
+			return Value{}, true, &TypeError{Name: name, Want: "numbers", Kinds: valueKinds(args)}
+		}
+		ints = ints && a.kind == IntKind
+	}
+	// 整数参数优先使用有理数实现，结果为整数时保持int64
+	if fn := ratFunc(def); fn != nil && ints {
+		rats := make([]*big.Rat, len(args))
+		for i, a := range args {
+			rats[i] = new(big.Rat).SetInt64(a.i)
+		}
+		r, err := fn(rats)
+		if err != nil {
+			return Value{}, true, err
+		}
+		if r.IsInt() && r.Num().IsInt64() {
+			return IntValue(r.Num().Int64()), true, nil
+		}
+		f, _ := r.Float64()
+		return FloatValue(f), true, nil
+	}
+	return Value{}, false, nil
+}
+
+func valueKinds(args []Value) []ValueKind {
+	kinds := make([]ValueKind, len(args))
+	for i, a := range args {
+		kinds[i] = a.kind
+	}
+	return kinds
+}
+
+func addInt(x, y int64) (int64, bool) {
+	r := x + y
+	return r, (r > x) == (y > 0)
+}
+
+func subInt(x, y int64) (int64, bool) {
+	r := x - y
+	return r, (r < x) == (y > 0)
+}
+
+func mulInt(x, y int64) (int64, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	r := x * y
+	if r/y != x || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
+}
+
+// powInt 非负整数次幂
+func powInt(x, n int64) (int64, bool) {
+	r := int64(1)
+	for ; n > 0; n >>= 1 {
+		var ok bool
+		if n&1 == 1 {
+			if r, ok = mulInt(r, x); !ok {
+				return 0, false
+			}
+		}
+		if n > 1 {
+			if x, ok = mulInt(x, x); !ok {
+				return 0, false
+			}
+		}
+	}
+	return r, true
+}
+
+// equalValues 数值按大小比较，其他类型须类型相同且值相等
+func equalValues(a Value, b Value) bool {
+	if a.kind == IntKind && b.kind == IntKind {
+		return a.i == b.i
+	}
+	if a.isNumber() && b.isNumber() {
+		x, _ := a.Float()
+		y, _ := b.Float()
+		return x == y
+	}
+	return a == b
+}
+
+// compareValues 比较数值或字符串
+func compareValues(op string, a Value, b Value) (int, error) {
+	switch {
+	case a.kind == IntKind && b.kind == IntKind:
+		switch {
+		case a.i < b.i:
+			return -1, nil
+		case a.i > b.i:
+			return 1, nil
+		}
+		return 0, nil
+	case a.isNumber() && b.isNumber():
+		x, _ := a.Float()
+		y, _ := b.Float()
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		case x == y:
+			return 0, nil
+		}
+		// NaN与任何值比较均为假
+		return 2, nil
+	case a.kind == StringKind && b.kind == StringKind:
+		return strings.Compare(a.s, b.s), nil
+	}
+	return 0, &TypeError{Name: op, Want: "numbers or strings", Kinds: []ValueKind{a.kind, b.kind}}
+}
+
+// valueFunc 以Value参数计算的标准库函数
+type valueFunc struct {
+	*stdFunc
+	value func(args []Value) (Value, error)
+}
+
+func (f *valueFunc) CallValue(args []Value) (Value, error) {
+	if err := f.checkArgc(len(args)); err != nil {
+		return Value{}, err
+	}
+	return f.value(args)
+}
+
+// Evaluate 数值计算中使用时，参数按Value计算，结果须为数值或布尔值
+func (f *valueFunc) Evaluate(ctx context.Context, args ...ExprNode) (float64, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		v, err := EvaluateValue(ctx, arg)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = v
+	}
+	v, err := f.CallValue(values)
+	if err != nil {
+		return 0, err
+	}
+	return valueToFloat(f.name, v)
+}
+
+func (f *valueFunc) Calculate(ctx context.Context, args ...ExprNode) float64 {
+	v, err := f.Evaluate(ctx, args...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (f *valueFunc) CallFloat(args []float64) (float64, error) {
+	values := make([]Value, len(args))
+	for i, a := range args {
+		values[i] = FloatValue(a)
+	}
+	v, err := f.CallValue(values)
+	if err != nil {
+		return 0, err
+	}
+	return valueToFloat(f.name, v)
+}
+
+func valueToFloat(name string, v Value) (float64, error) {
+	switch v.kind {
+	case IntKind, FloatKind, BoolKind:
+		return valueArith{}.toFloat(v), nil
+	}
+	return 0, &TypeError{Name: name, Want: "number result", Kinds: []ValueKind{v.kind}}
+}
+
+// stringArg 获取字符串参数
+func stringArg(name string, args []Value, i int) (string, error) {
+	if s, ok := args[i].Text(); ok {
+		return s, nil
+	}
+	return "", &TypeError{Name: name, Want: "string", Kinds: []ValueKind{args[i].kind}}
+}
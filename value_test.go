@@ -0,0 +1,61 @@
+package mathastc
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestEvaluateValue(t *testing.T) {
+	ctx := varsCtx(map[string]any{"n": 3, "name": "abc"})
+	tests := []struct {
+		src  string
+		want Value
+	}{
+		{"1+2", IntValue(3)},
+		{"7%3", IntValue(1)},
+		{"2^10", IntValue(1024)},
+		{"1+0.5", FloatValue(1.5)},
+		{"7/2", FloatValue(3.5)},
+		{"n*2", IntValue(6)},
+		{"n > 2", BoolValue(true)},
+		{`"a" + "b"`, StringValue("ab")},
+		{`name == "abc"`, BoolValue(true)},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateValue(ctx, mustParse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v (%v), want %v (%v)", tt.src, got, got.Kind(), tt.want, tt.want.Kind())
+		}
+	}
+}
+
+func TestEvaluateValueOverflow(t *testing.T) {
+	ctx := varsCtx(map[string]any{"max": int64(math.MaxInt64), "min": int64(math.MinInt64)})
+	for _, src := range []string{"max+1", "min-1", "max*2", "2^64", "-min"} {
+		expr := mustParse(t, src)
+		got, err := EvaluateValue(ctx, expr)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got.Kind() != FloatKind {
+			t.Errorf("%s = %v, want float", src, got)
+		}
+		f, _ := got.Float()
+		if want, _ := Evaluate(ctx, expr); f != want {
+			t.Errorf("%s = %v, want %v", src, f, want)
+		}
+	}
+}
+
+func TestEvaluateValueTypeError(t *testing.T) {
+	_, err := EvaluateValue(context.Background(), mustParse(t, `"a" * 2`))
+	if err == nil {
+		t.Fatal("want type error")
+	}
+}
@@ -0,0 +1,261 @@
+package mathastc
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// CompositeNode 可访问子节点的节点，内置节点均已实现，未实现的自定义节点视为叶子节点
+type CompositeNode interface {
+	ExprNode
+	// Children 子节点，叶子节点返回nil
+	Children() []ExprNode
+	// WithChildren 以新的子节点创建节点，子节点个数须与Children一致
+	WithChildren(children []ExprNode) ExprNode
+}
+
+func checkChildren(node ExprNode, want int, children []ExprNode) {
+	if len(children) != want {
+		panic(fmt.Sprintf("%T.WithChildren: want %d children but get %d", node, want, len(children)))
+	}
+}
+
+func (n NumberExprNode) Children() []ExprNode {
+	return nil
+}
+
+func (n NumberExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(n, 0, children)
+	return n
+}
+
+func (o OperatorExprNode) Children() []ExprNode {
+	return []ExprNode{o.Lhs, o.Rhs}
+}
+
+func (o OperatorExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(o, 2, children)
+	o.Lhs, o.Rhs = children[0], children[1]
+	return o
+}
+
+func (u UnaryExprNode) Children() []ExprNode {
+	return []ExprNode{u.Operand}
+}
+
+func (u UnaryExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(u, 1, children)
+	u.Operand = children[0]
+	return u
+}
+
+func (f FunCallerExprNode) Children() []ExprNode {
+	return f.Arg
+}
+
+func (f FunCallerExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(f, len(f.Arg), children)
+	f.Arg = append([]ExprNode{}, children...)
+	return f
+}
+
+func (v VariableExprNode) Children() []ExprNode {
+	return nil
+}
+
+func (v VariableExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(v, 0, children)
+	return v
+}
+
+func (c ConditionalExprNode) Children() []ExprNode {
+	return []ExprNode{c.Cond, c.Then, c.Else}
+}
+
+func (c ConditionalExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(c, 3, children)
+	c.Cond, c.Then, c.Else = children[0], children[1], children[2]
+	return c
+}
+
+func (c ConstExprNode) Children() []ExprNode {
+	return nil
+}
+
+func (c ConstExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(c, 0, children)
+	return c
+}
+
+func (s StringExprNode) Children() []ExprNode {
+	return nil
+}
+
+func (s StringExprNode) WithChildren(children []ExprNode) ExprNode {
+	checkChildren(s, 0, children)
+	return s
+}
+
+// Children 获取节点的子节点
+func Children(expr ExprNode) []ExprNode {
+	if c, ok := expr.(CompositeNode); ok {
+		return c.Children()
+	}
+	return nil
+}
+
+// Visitor 节点访问器，Visit返回nil时不再访问子节点
+type Visitor interface {
+	Visit(node ExprNode) (w Visitor)
+}
+
+// Walk 前序遍历表达式
+func Walk(v Visitor, expr ExprNode) {
+	if expr == nil {
+		return
+	}
+	if v = v.Visit(expr); v == nil {
+		return
+	}
+	for _, child := range Children(expr) {
+		Walk(v, child)
+	}
+}
+
+type inspector func(ExprNode) bool
+
+func (f inspector) Visit(node ExprNode) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 前序遍历表达式，fn返回false时不再访问子节点
+func Inspect(expr ExprNode, fn func(node ExprNode) bool) {
+	Walk(inspector(fn), expr)
+}
+
+// Rewrite 自底向上改写表达式，子节点改写后再以新节点调用fn，fn返回true时替换为返回的节点
+// 原表达式不会被修改
+func Rewrite(expr ExprNode, fn func(node ExprNode) (ExprNode, bool)) ExprNode {
+	r, _ := rewrite(expr, fn)
+	return r
+}
+
+func rewrite(expr ExprNode, fn func(node ExprNode) (ExprNode, bool)) (ExprNode, bool) {
+	if expr == nil {
+		return nil, false
+	}
+	changed := false
+	if c, ok := expr.(CompositeNode); ok {
+		children := c.Children()
+		var rewritten []ExprNode
+		for i, child := range children {
+			r, ok := rewrite(child, fn)
+			if ok && rewritten == nil {
+				rewritten = append(make([]ExprNode, 0, len(children)), children[:i]...)
+			}
+			if rewritten != nil {
+				rewritten = append(rewritten, r)
+			}
+		}
+		if rewritten != nil {
+			expr = c.WithChildren(rewritten)
+			changed = true
+		}
+	}
+	if r, ok := fn(expr); ok {
+		return r, true
+	}
+	return expr, changed
+}
+
+// EqualExpr 结构相等，忽略源码位置及数值的字面写法
+func EqualExpr(a ExprNode, b ExprNode) bool {
+	switch x := a.(type) {
+	case NumberExprNode:
+		y, ok := b.(NumberExprNode)
+		return ok && x.Val == y.Val
+	case ConstExprNode:
+		y, ok := b.(ConstExprNode)
+		return ok && x.Name == y.Name
+	case VariableExprNode:
+		y, ok := b.(VariableExprNode)
+		return ok && x.Val == y.Val
+	case StringExprNode:
+		y, ok := b.(StringExprNode)
+		return ok && x.Val == y.Val
+	case OperatorExprNode:
+		y, ok := b.(OperatorExprNode)
+		return ok && x.Op == y.Op && EqualExpr(x.Lhs, y.Lhs) && EqualExpr(x.Rhs, y.Rhs)
+	case UnaryExprNode:
+		y, ok := b.(UnaryExprNode)
+		return ok && x.Op == y.Op && EqualExpr(x.Operand, y.Operand)
+	case ConditionalExprNode:
+		y, ok := b.(ConditionalExprNode)
+		return ok && EqualExpr(x.Cond, y.Cond) && EqualExpr(x.Then, y.Then) && EqualExpr(x.Else, y.Else)
+	case FunCallerExprNode:
+		y, ok := b.(FunCallerExprNode)
+		if !ok || x.Name != y.Name || len(x.Arg) != len(y.Arg) {
+			return false
+		}
+		for i := range x.Arg {
+			if !EqualExpr(x.Arg[i], y.Arg[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// HashExpr 结构哈希，EqualExpr的两个表达式哈希相同
+func HashExpr(expr ExprNode) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	writeFloat := func(f float64) {
+		if f == 0 {
+			f = 0 // -0与0相等
+		}
+		bits := math.Float64bits(f)
+		for i := range buf {
+			buf[i] = byte(bits >> (8 * i))
+		}
+		_, _ = h.Write(buf[:])
+	}
+	writeStr := func(s string) {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	Inspect(expr, func(node ExprNode) bool {
+		writeStr(fmt.Sprintf("%T", node))
+		switch n := node.(type) {
+		case NumberExprNode:
+			writeFloat(n.Val)
+		case ConstExprNode:
+			writeStr(n.Name)
+		case VariableExprNode:
+			writeStr(n.Val)
+		case StringExprNode:
+			writeStr(n.Val)
+		case OperatorExprNode:
+			writeStr(n.Op)
+		case UnaryExprNode:
+			writeStr(n.Op)
+		case FunCallerExprNode:
+			writeStr(n.Name)
+			writeFloat(float64(len(n.Arg)))
+		case ConditionalExprNode:
+			// 仅由子节点决定
+		default:
+			writeStr(n.ToStr())
+		}
+		return true
+	})
+	return h.Sum64()
+}
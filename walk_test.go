@@ -0,0 +1,68 @@
+package mathastc
+
+import "testing"
+
+func TestInspect(t *testing.T) {
+	var visited []string
+	Inspect(mustParse(t, "a + max(b, -c) * (d ? 1 : 2)"), func(node ExprNode) bool {
+		switch n := node.(type) {
+		case VariableExprNode:
+			visited = append(visited, n.Val)
+		case FunCallerExprNode:
+			visited = append(visited, n.Name+"()")
+			return false
+		}
+		return true
+	})
+	if want := []string{"a", "max()", "d"}; !equalStrings(visited, want) {
+		t.Errorf("Inspect = %v, want %v", visited, want)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	expr := mustParse(t, "x * 2 + max(x, y)")
+	got := Rewrite(expr, func(node ExprNode) (ExprNode, bool) {
+		if v, ok := node.(VariableExprNode); ok && v.Val == "x" {
+			return VariableExprNode{Val: "z"}, true
+		}
+		return nil, false
+	})
+	if s := Format(got); s != "z * 2 + max(z, y)" {
+		t.Errorf("Rewrite = %s", s)
+	}
+	if s := Format(expr); s != "x * 2 + max(x, y)" {
+		t.Errorf("Rewrite modified the original expression: %s", s)
+	}
+}
+
+func TestEqualAndHashExpr(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"1.50 + x", "1.5+x", true},
+		{"(a*b)", "a * b", true},
+		{"a * b", "b * a", false},
+		{"max(x, y)", "max(x)", false},
+		{"-x", "0 - x", false},
+		{`"s"`, `"s"`, true},
+	}
+	for _, tt := range tests {
+		a, b := mustParse(t, tt.a), mustParse(t, tt.b)
+		if got := EqualExpr(a, b); got != tt.equal {
+			t.Errorf("EqualExpr(%s, %s) = %v", tt.a, tt.b, got)
+		}
+		if tt.equal && HashExpr(a) != HashExpr(b) {
+			t.Errorf("HashExpr(%s) != HashExpr(%s)", tt.a, tt.b)
+		}
+	}
+}
+
+func TestWithChildrenPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic for wrong child count")
+		}
+	}()
+	mustParse(t, "a + b").(OperatorExprNode).WithChildren([]ExprNode{VariableExprNode{Val: "a"}})
+}